@@ -3,6 +3,7 @@ package godac
 import (
 	"fmt"
 	"godac/sqlbuilder"
+	"strings"
 )
 
 // CheckerFunc is used for some checks.
@@ -22,13 +23,30 @@ func Exists(field Field, table *Table) CheckerFunc {
 	}
 }
 
+// The error formatted by ErrorFormatOnDelete, it is not formatted again by the Deleter of a cascading parent.
+type deleteError struct {
+	text string
+	code int
+}
+
+func (e deleteError) Error() string {
+	return e.text
+}
+
+func (e deleteError) Code() int {
+	return e.code
+}
+
 // Deleter to get a OnDelete function by checkers.
 func Deleter(checkers ...CheckerFunc) ActionFunc {
 	return func(c Context) (Result, error) {
 		for _, checker := range checkers {
 			if err := checker(c); err != nil {
+				if _, ok := err.(deleteError); ok {
+					return nil, err
+				}
 				if e, ok := err.(Error); ok {
-					return nil, NewError(fmt.Sprintf(ErrorFormatOnDelete, e.Error()), e.Code())
+					return nil, deleteError{fmt.Sprintf(ErrorFormatOnDelete, e.Error()), e.Code()}
 				}
 				return nil, err
 			}
@@ -36,3 +54,90 @@ func Deleter(checkers ...CheckerFunc) ActionFunc {
 		return c.Table.DefaultDelete(c)
 	}
 }
+
+// DeleteAction is the action on the related records of HasMany and ManyToMany relation when a record is deleted.
+type DeleteAction byte
+
+// DeleteActions enum.
+const (
+	DeleteNoAction DeleteAction = iota // Do nothing, the database may reject the delete by foreign key.
+	DeleteRestrict                     // Reject the delete if related records exist.
+	DeleteCascade                      // Delete the related records by Delete of the related table, ManyToMany deletes the join records only.
+	DeleteSetNull                      // Set the referencing columns of the related records to NULL, ManyToMany deletes the join records.
+)
+
+// DeleteRelations is a checker that applies OnDelete actions of the relations of c.Table,
+// it is used with Deleter, e.g. Deleter(DeleteRelations). All restricted relations are checked first,
+// and the titles of tables which have related records are listed in the error. Then the related records are
+// deleted or nullified in order of relations, with c.DB, so they are in the transaction of caller.
+func DeleteRelations(c Context) error {
+	var relations []Relation
+	for _, v := range c.Table.Relations {
+		if v.Kind == RelationBelongsTo || v.OnDelete == DeleteNoAction {
+			continue
+		}
+		rel, err := c.Table.Relation(v.Name)
+		if err != nil {
+			return err
+		}
+		relations = append(relations, rel)
+	}
+
+	var titles []string
+	for _, rel := range relations {
+		if rel.OnDelete != DeleteRestrict {
+			continue
+		}
+		count, err := rel.count(c.Table, c.DB, c.Record)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			titles = append(titles, rel.Table.GetTitle())
+		}
+	}
+	if len(titles) > 0 {
+		return NewError(fmt.Sprintf(ErrorFormatOnRestrict, strings.Join(titles, ", ")), ErrExists.Code())
+	}
+
+	for _, rel := range relations {
+		if rel.OnDelete == DeleteRestrict {
+			continue
+		}
+		query, args, err := rel.where(c.Table, c.Record)
+		if err != nil {
+			return err
+		}
+		switch {
+		case rel.Kind == RelationManyToMany:
+			_, err = dbExec(c.DB, fmt.Sprintf("DELETE FROM %s WHERE %s", rel.Through.Name, query), args...)
+		case rel.OnDelete == DeleteCascade:
+			err = cascadeDelete(c.DB, rel.Table, query, args)
+		case rel.OnDelete == DeleteSetNull:
+			sets := make([]string, len(rel.References))
+			for i, column := range rel.References {
+				sets[i] = column + " = NULL"
+			}
+			query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", rel.Table.Name, strings.Join(sets, sqlbuilder.ColSep), query)
+			_, err = dbExec(c.DB, query, args...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the records of condition one by one by Delete of table, so its OnDelete runs.
+func cascadeDelete(db DB, table *Table, condition string, args []interface{}) error {
+	rows, err := table.Select(db, sqlbuilder.Select().Where(condition), args...)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := table.Delete(db, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
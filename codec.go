@@ -0,0 +1,192 @@
+package godac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Codec converts field value between Go value and database column value.
+type Codec interface {
+	Encode(value interface{}) (interface{}, error) // Encode value before INSERT/UPDATE.
+	Decode(value interface{}) (interface{}, error) // Decode value after SELECT.
+}
+
+// Encode field value by Codec, nil, Null and Expr are not encoded.
+func (field Field) encode(value interface{}) (interface{}, error) {
+	if _, ok := value.(Expr); ok || field.Codec == nil || isNull(value) {
+		return value, nil
+	}
+	value, err := field.Codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field.GetTitle(), err)
+	}
+	return value, nil
+}
+
+// Decode field value by Codec, nil is not decoded.
+func (field Field) decode(value interface{}) (interface{}, error) {
+	if field.Codec == nil || isNull(value) {
+		return value, nil
+	}
+	return field.Codec.Decode(value)
+}
+
+func codecText(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// JSONCodec stores value as JSON text.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (jsonCodec) Decode(value interface{}) (interface{}, error) {
+	text, ok := codecText(value)
+	if !ok {
+		return value, nil
+	}
+	var result interface{}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListCodec return a codec that stores []string as text delimited by sep.
+func ListCodec(sep string) Codec {
+	return listCodec(sep)
+}
+
+type listCodec string
+
+func (sep listCodec) Encode(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, string(sep)), nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, item := range v {
+			list[i] = fmt.Sprint(item)
+		}
+		return strings.Join(list, string(sep)), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T value as list", value)
+}
+
+func (sep listCodec) Decode(value interface{}) (interface{}, error) {
+	text, ok := codecText(value)
+	if !ok {
+		return value, nil
+	}
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, string(sep)), nil
+}
+
+// KeyProvider provides the encryption key.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyFunc is an adapter to use function as KeyProvider.
+type KeyFunc func() ([]byte, error)
+
+// Key implements KeyProvider.
+func (f KeyFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// StaticKey is a KeyProvider of fixed key.
+type StaticKey []byte
+
+// Key implements KeyProvider.
+func (key StaticKey) Key() ([]byte, error) {
+	return key, nil
+}
+
+// AESGCMCodec return a codec that stores string encrypted by AES-GCM, in base64 text of nonce and ciphertext.
+// The key length must be 16, 24 or 32 bytes.
+func AESGCMCodec(keys KeyProvider) Codec {
+	return aesGCMCodec{keys}
+}
+
+type aesGCMCodec struct {
+	keys KeyProvider
+}
+
+func (codec aesGCMCodec) aead() (cipher.AEAD, error) {
+	key, err := codec.keys.Key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (codec aesGCMCodec) Encode(value interface{}) (interface{}, error) {
+	text, ok := codecText(value)
+	if !ok {
+		text = fmt.Sprint(value)
+	}
+	aead, err := codec.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	data := aead.Seal(nonce, nonce, []byte(text), nil)
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (codec aesGCMCodec) Decode(value interface{}) (interface{}, error) {
+	text, ok := codecText(value)
+	if !ok {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := codec.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	return string(plain), nil
+}
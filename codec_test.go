@@ -0,0 +1,37 @@
+package godac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecs(t *testing.T) {
+	tests := []struct {
+		codec Codec
+		value interface{}
+		want  interface{}
+	}{
+		{JSONCodec, map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 1.0}},
+		{ListCodec(","), []string{"a", "b"}, []string{"a", "b"}},
+		{AESGCMCodec(StaticKey("0123456789abcdef")), "secret", "secret"},
+	}
+	for _, test := range tests {
+		encoded, err := test.codec.Encode(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := test.codec.Decode([]byte(encoded.(string)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, test.want) {
+			t.Errorf("%T: decoded %#v, want %#v", test.codec, decoded, test.want)
+		}
+	}
+
+	codec := AESGCMCodec(StaticKey("0123456789abcdef"))
+	encoded, _ := codec.Encode("secret")
+	if _, err := AESGCMCodec(StaticKey("fedcba9876543210")).Decode(encoded); err == nil {
+		t.Error("decrypt with wrong key should fail")
+	}
+}
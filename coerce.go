@@ -0,0 +1,232 @@
+package godac
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Type is the logical type of Field value.
+type Type byte
+
+// Types enum.
+const (
+	TypeAny Type = iota
+	TypeString
+	TypeInt
+	TypeUint
+	TypeFloat
+	TypeDecimal
+	TypeBool
+	TypeTime
+	TypeDate
+)
+
+// Coercion errors.
+var (
+	ErrCoerceString  = validation.NewError("coerce_string", "must be a string")
+	ErrCoerceInt     = validation.NewError("coerce_int", "must be an integer")
+	ErrCoerceUint    = validation.NewError("coerce_uint", "must be a non-negative integer")
+	ErrCoerceFloat   = validation.NewError("coerce_float", "must be a number")
+	ErrCoerceDecimal = validation.NewError("coerce_decimal", "must be a decimal number")
+	ErrCoerceBool    = validation.NewError("coerce_bool", "must be a boolean")
+	ErrCoerceTime    = validation.NewError("coerce_time", "must be a valid time")
+	ErrCoerceDate    = validation.NewError("coerce_date", "must be a valid date")
+)
+
+// DateLayout is the layout of parsing TypeDate text.
+var DateLayout = "2006-01-02"
+
+// Coerce converts value to the Go type of Field.Type:
+// TypeString to string, TypeInt to int64, TypeUint to uint64, TypeFloat to float64, TypeDecimal to Decimal,
+// TypeBool to bool, TypeTime and TypeDate to time.Time. Empty string is converted to Null if Field is Nullable.
+func (field Field) Coerce(value interface{}) (interface{}, error) {
+	if _, ok := value.(Expr); ok || isNull(value) || field.Type == TypeAny {
+		return value, nil
+	}
+	if s, ok := value.(string); ok {
+		if strings.TrimSpace(s) == "" {
+			if field.Nullable {
+				return Null, nil
+			}
+			if field.Type != TypeString {
+				return nil, validation.ErrRequired
+			}
+		}
+	}
+	switch field.Type {
+	case TypeString:
+		return coerceString(value)
+	case TypeInt:
+		return coerceInt(value)
+	case TypeUint:
+		return coerceUint(value)
+	case TypeFloat:
+		return coerceFloat(value)
+	case TypeDecimal:
+		return coerceDecimal(value)
+	case TypeBool:
+		return coerceBool(value)
+	case TypeTime:
+		return coerceTime(value)
+	case TypeDate:
+		return coerceDate(value)
+	}
+	return value, nil
+}
+
+func coerceString(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	}
+	return nil, ErrCoerceString
+}
+
+func coerceInt(value interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() <= math.MaxInt64 {
+			return int64(rv.Uint()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f <= math.MaxInt64 {
+			return int64(f), nil
+		}
+	case reflect.String:
+		if i, err := strconv.ParseInt(strings.TrimSpace(rv.String()), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return nil, ErrCoerceInt
+}
+
+func coerceUint(value interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() >= 0 {
+			return uint64(rv.Int()), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= 0 && f <= math.MaxUint64 {
+			return uint64(f), nil
+		}
+	case reflect.String:
+		if u, err := strconv.ParseUint(strings.TrimSpace(rv.String()), 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return nil, ErrCoerceUint
+}
+
+func coerceFloat(value interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, ErrCoerceFloat
+}
+
+func coerceDecimal(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case Decimal:
+		return v, nil
+	case string:
+		if d, err := ParseDecimal(strings.TrimSpace(v)); err == nil {
+			return d, nil
+		}
+	case json.Number:
+		if d, err := ParseDecimal(v.String()); err == nil {
+			return d, nil
+		}
+	case float64:
+		return Decimal(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case int64:
+		return Decimal(strconv.FormatInt(v, 10)), nil
+	case int:
+		return Decimal(strconv.Itoa(v)), nil
+	}
+	return nil, ErrCoerceDecimal
+}
+
+func coerceBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return b, nil
+		}
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case int64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	}
+	return nil, ErrCoerceBool
+}
+
+func coerceTime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		for _, layout := range TimeLayouts {
+			if t, err := time.ParseInLocation(layout, v, DefaultDialect.location()); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return nil, ErrCoerceTime
+}
+
+func coerceDate(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.ParseInLocation(DateLayout, strings.TrimSpace(v), DefaultDialect.location()); err == nil {
+			return t, nil
+		}
+	}
+	return nil, ErrCoerceDate
+}
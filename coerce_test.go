@@ -0,0 +1,62 @@
+package godac
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func errorCode(err error) string {
+	if e, ok := err.(validation.Error); ok {
+		return e.Code()
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		field Field
+		value interface{}
+		want  interface{}
+		err   validation.Error
+	}{
+		{Field{Type: TypeInt}, float64(42), int64(42), nil},
+		{Field{Type: TypeInt}, 4.2, nil, ErrCoerceInt},
+		{Field{Type: TypeInt}, " 7 ", int64(7), nil},
+		{Field{Type: TypeUint}, float64(-1), nil, ErrCoerceUint},
+		{Field{Type: TypeBool}, "true", true, nil},
+		{Field{Type: TypeBool}, "yes", nil, ErrCoerceBool},
+		{Field{Type: TypeDecimal}, "1.50", Decimal("1.50"), nil},
+		{Field{Type: TypeDecimal}, json.Number("2.5"), Decimal("2.5"), nil},
+		{Field{Type: TypeDecimal}, json.Number("1/2"), nil, ErrCoerceDecimal},
+		{Field{Type: TypeString, Nullable: true}, "", Null, nil},
+		{Field{Type: TypeString}, "", "", nil},
+		{Field{Type: TypeDate, Nullable: true}, " ", Null, nil},
+		{Field{Type: TypeInt}, Null, Null, nil},
+		{Field{Type: TypeTime}, "2020-01-02T03:04:05Z", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), nil},
+	}
+	for i, test := range tests {
+		got, err := test.field.Coerce(test.value)
+		if errorCode(err) != errorCode(test.err) {
+			t.Errorf("%d: error %v, want %v", i, err, test.err)
+			continue
+		}
+		if tm, ok := got.(time.Time); ok {
+			if !tm.Equal(test.want.(time.Time)) {
+				t.Errorf("%d: got %v, want %v", i, got, test.want)
+			}
+		} else if got != test.want {
+			t.Errorf("%d: got %#v, want %#v", i, got, test.want)
+		}
+	}
+
+	err := fieldError(Field{Name: "age"}, ErrCoerceInt)
+	if err.Error() != "Age: must be an integer" {
+		t.Errorf("fieldError = %q", err)
+	}
+}
@@ -0,0 +1,134 @@
+package godac
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect defines the differences of databases.
+type Dialect struct {
+	Name                 string
+	Types                TypeMap        // Type mappings of column values, by database type name.
+	Location             *time.Location // The location of DATETIME/TIMESTAMP values, nil means UTC.
+	NumberedPlaceholders bool           // Placeholder ? is rewritten to $1, $2...
+	Returning            []State        // The states of INSERT/UPDATE/DELETE statement that support RETURNING clause.
+}
+
+// Dialects definition.
+var (
+	MySQL = &Dialect{Name: "mysql", Types: TypeMap{
+		"TINYINT": ToInt64, "SMALLINT": ToInt64, "MEDIUMINT": ToInt64, "INT": ToInt64, "BIGINT": ToInt64,
+		"YEAR": ToInt64, "UNSIGNED BIGINT": ToUint64,
+		"FLOAT": ToFloat64, "DOUBLE": ToFloat64, "REAL": ToFloat64,
+		"BOOL": ToBool, "BOOLEAN": ToBool,
+		"DECIMAL": ToDecimal,
+		"CHAR":    ToString, "VARCHAR": ToString, "TINYTEXT": ToString, "TEXT": ToString, "MEDIUMTEXT": ToString,
+		"LONGTEXT": ToString, "ENUM": ToString, "SET": ToString, "DATE": ToString, "TIME": ToString,
+		"DATETIME": ToTime, "TIMESTAMP": ToTime,
+		"JSON":   ToJSON,
+		"BINARY": ToBytes, "VARBINARY": ToBytes, "TINYBLOB": ToBytes, "BLOB": ToBytes, "MEDIUMBLOB": ToBytes,
+		"LONGBLOB": ToBytes, "BIT": ToBytes,
+	}}
+	MariaDB    = &Dialect{Name: "mariadb", Types: MySQL.Types.Clone(), Returning: []State{StateInsert, StateDelete}}
+	PostgreSQL = &Dialect{Name: "postgres", NumberedPlaceholders: true, Returning: []State{StateInsert, StateUpdate, StateDelete}, Types: TypeMap{
+		"INT2": ToInt64, "INT4": ToInt64, "INT8": ToInt64,
+		"FLOAT4": ToFloat64, "FLOAT8": ToFloat64,
+		"BOOL":    ToBool,
+		"NUMERIC": ToDecimal,
+		"CHAR":    ToString, "BPCHAR": ToString, "VARCHAR": ToString, "TEXT": ToString, "UUID": ToString,
+		"DATE": ToString, "TIME": ToString,
+		"TIMESTAMP": ToTime, "TIMESTAMPTZ": ToTime,
+		"JSON": ToJSON, "JSONB": ToJSON,
+		"BYTEA": ToBytes,
+	}}
+	SQLite = &Dialect{Name: "sqlite", Returning: []State{StateInsert, StateUpdate, StateDelete}, Types: TypeMap{
+		"INT": ToInt64, "INTEGER": ToInt64, "BIGINT": ToInt64,
+		"REAL": ToFloat64, "FLOAT": ToFloat64, "DOUBLE": ToFloat64,
+		"BOOL": ToBool, "BOOLEAN": ToBool,
+		"NUMERIC": ToDecimal, "DECIMAL": ToDecimal,
+		"TEXT": ToString, "VARCHAR": ToString, "CHAR": ToString, "DATE": ToString, "TIME": ToString,
+		"DATETIME": ToTime, "TIMESTAMP": ToTime,
+		"JSON": ToJSON,
+		"BLOB": ToBytes,
+	}}
+)
+
+// DefaultDialect is the dialect used by all queries.
+var DefaultDialect = MySQL
+
+// Get the location of DATETIME/TIMESTAMP values.
+func (dialect *Dialect) location() *time.Location {
+	if dialect.Location == nil {
+		return time.UTC
+	}
+	return dialect.Location
+}
+
+// Get the type mapping of column, the database type name is prefixed by "UNSIGNED " for unsigned integers.
+// The unsigned integer is detected by the scan type of driver, it is not detected if the driver scans
+// a nullable column as sql.NullInt64 like mysql v1.5.0 does, use Field.Mapping to force it, e.g. ToUint64.
+func (dialect *Dialect) mapping(col *sql.ColumnType) TypeMapping {
+	name := col.DatabaseTypeName()
+	if scanType := col.ScanType(); scanType != nil {
+		switch scanType.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if mapping, ok := dialect.Types["UNSIGNED "+name]; ok {
+				return mapping
+			}
+		}
+	}
+	return dialect.Types[name]
+}
+
+// Check the statement of state supports RETURNING clause.
+func (dialect *Dialect) returning(state State) bool {
+	for _, v := range dialect.Returning {
+		if v == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Rebind rewrite placeholders of query for the dialect, placeholders in quoted text are ignored.
+func (dialect *Dialect) Rebind(query string) string {
+	if !dialect.NumberedPlaceholders || !strings.Contains(query, Placeholder) {
+		return query
+	}
+	var b strings.Builder
+	var quote rune
+	n := 0
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// The executions of the default dialect.
+
+func dbQuery(db DB, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Query(DefaultDialect.Rebind(query), args...)
+}
+
+func dbQueryRow(db DB, query string, args ...interface{}) *sql.Row {
+	return db.QueryRow(DefaultDialect.Rebind(query), args...)
+}
+
+func dbExec(db DB, query string, args ...interface{}) (sql.Result, error) {
+	return db.Exec(DefaultDialect.Rebind(query), args...)
+}
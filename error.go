@@ -1,18 +1,32 @@
 package godac
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
 // Error variables definition.
 var (
-	ErrExists = NewError("this record is in use", 400)
+	ErrExists   = NewError("this record is in use", 400)
+	ErrNotFound = NewError("record not found", 404)
 )
 
 // Error formats.
 var (
 	ErrorFormatOnDelete = "can not be deleted, %s"
+	// %s represents the titles of tables which have related records.
+	ErrorFormatOnRestrict = "it is in use by %s"
 	// Define field validation error format.
 	// %s represents field title, %v represents validation error, field must be in front of the error.
 	ErrorFormatOnValidation = "%s: %v"
 )
 
+// ErrorCodeOnValidation is the code of ValidationError.
+var ErrorCodeOnValidation = 422
+
 // Error define an error that include error code.
 type Error interface {
 	error
@@ -36,3 +50,68 @@ func (e *errorObject) Code() int {
 func NewError(text string, code int) Error {
 	return &errorObject{text, code}
 }
+
+// FieldError is the validation error of a field.
+type FieldError struct {
+	Title   string `json:"title"`   // Field title
+	Code    string `json:"code"`    // Validation rule error code
+	Message string `json:"message"` // Error message without title
+}
+
+// ValidationError contains the validation errors of record by field Map key,
+// it implements Error with code ErrorCodeOnValidation.
+type ValidationError map[string]FieldError
+
+// Error implements error, the errors of fields are formatted by ErrorFormatOnValidation and sorted by key.
+func (e ValidationError) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := make([]string, len(keys))
+	for i, key := range keys {
+		list[i] = fmt.Sprintf(ErrorFormatOnValidation, e[key].Title, e[key].Message)
+	}
+	return strings.Join(list, "; ")
+}
+
+// Code implements Error.
+func (e ValidationError) Code() int {
+	return ErrorCodeOnValidation
+}
+
+// Add the error of field if it is a validation.Error and field has no error yet, the other errors are returned.
+func (e ValidationError) Add(key string, field Field, err error) error {
+	ve, ok := err.(validation.Error)
+	if !ok {
+		return err
+	}
+	if _, exists := e[key]; !exists {
+		e[key] = FieldError{field.GetTitle(), ve.Code(), ve.Error()}
+	}
+	return nil
+}
+
+// Merge err into e if it is a ValidationError, the keys which already have an error are skipped.
+// The other errors are returned.
+func (e ValidationError) merge(err error) error {
+	other, ok := err.(ValidationError)
+	if !ok {
+		return err
+	}
+	for key, fe := range other {
+		if _, exists := e[key]; !exists {
+			e[key] = fe
+		}
+	}
+	return nil
+}
+
+// Return e as error if it is not empty, otherwise nil.
+func (e ValidationError) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
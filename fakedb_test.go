@@ -0,0 +1,155 @@
+package godac
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database of the godactest driver, it logs the statements and answers queries by handler.
+type fakeDB struct {
+	mu      sync.Mutex
+	log     []string
+	lastID  int64
+	handler func(query string, args []driver.Value) (columns []string, rows [][]driver.Value)
+	types   map[string]fakeColumn // Column types by column name, the columns are untyped if absent.
+}
+
+// fakeColumn is the database type of a column.
+type fakeColumn struct {
+	Type     string
+	Nullable bool
+}
+
+var (
+	fakeDBs   = map[string]*fakeDB{}
+	fakeDBsMu sync.Mutex
+)
+
+func init() {
+	sql.Register("godactest", fakeDriver{})
+}
+
+// Open a fake database, handler may be nil to answer all queries with no rows.
+func openFakeDB(t *testing.T, handler func(query string, args []driver.Value) ([]string, [][]driver.Value)) (*sql.DB, *fakeDB) {
+	fake := &fakeDB{handler: handler}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = fake
+	fakeDBsMu.Unlock()
+	db, err := sql.Open("godactest", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// Statements get the logged statements, with the arguments appended.
+func (db *fakeDB) statements() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string{}, db.log...)
+}
+
+func (db *fakeDB) record(query string, args []driver.Value) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(args) > 0 {
+		query += fmt.Sprint(" ", args)
+	}
+	db.log = append(db.log, query)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	return fakeConn{fakeDBs[name]}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.db, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.db.record("BEGIN", nil)
+	return fakeTx{c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error   { tx.db.record("COMMIT", nil); return nil }
+func (tx fakeTx) Rollback() error { tx.db.record("ROLLBACK", nil); return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args)
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.db.mu.Lock()
+		s.db.lastID++
+		id := s.db.lastID
+		s.db.mu.Unlock()
+		return fakeResult{id}, nil
+	}
+	return fakeResult{}, nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query, args)
+	var columns []string
+	var rows [][]driver.Value
+	if s.db.handler != nil {
+		columns, rows = s.db.handler(s.query, args)
+	}
+	if columns == nil && strings.Contains(s.query, "COUNT(*)") {
+		columns, rows = []string{"count"}, [][]driver.Value{{int64(0)}}
+	}
+	s.db.mu.Lock()
+	types := s.db.types
+	s.db.mu.Unlock()
+	return &fakeRows{columns: columns, rows: rows, types: types}, nil
+}
+
+type fakeResult struct{ id int64 }
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	types   map[string]fakeColumn
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[r.columns[index]].Type
+}
+
+func (r *fakeRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	column, ok := r.types[r.columns[index]]
+	return column.Nullable, ok
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
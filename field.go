@@ -15,10 +15,15 @@ type Field struct {
 	Title       string            // The caption of column, used for display validation errors, etc...
 	PrimaryKey  bool              // Is in primary key
 	AutoInc     bool              // Is auto-increment
-	ReadOnly    bool              // User cannot edit, it will be excluded on INSERT and UPDATE if Default or OnUpdate is nil.
-	Default     interface{}       // Default value on INSERT
-	OnUpdate    interface{}       // Value on UPDATE
+	Generator   Generator         // Generates value on INSERT when record value is nil or absent, e.g. UUIDv4
+	Type        Type              // Logical type, record values are coerced to it on INSERT and UPDATE
+	Nullable    bool              // Is nullable, empty string is coerced to NULL
+	ReadOnly    bool              // User cannot edit, it will be excluded on INSERT and UPDATE if Default/Generator or OnUpdate is nil.
+	Default     interface{}       // Default value on INSERT, used when record value is nil or absent, but not Null
+	OnUpdate    interface{}       // Value on UPDATE, used when record value is nil or absent, but not Null
 	Validations []validation.Rule // Validation rules
+	Mapping     TypeMapping       // Overrides the type mapping of column on SELECT
+	Codec       Codec             // Encode value on INSERT/UPDATE and decode value on SELECT
 }
 
 // GetKey get real JSON Key or Map key, may be do naming conversion.
@@ -96,6 +101,10 @@ func (field Field) GetTitle() string {
 // ValueFunc represents a get Default/OnUpdate value function.
 type ValueFunc func() interface{}
 
+// ContextValueFunc represents a get Default/OnUpdate value function in Context,
+// Context.Record contains the record values of previous fields and Context.Field is the field itself.
+type ContextValueFunc func(Context) interface{}
+
 // Now get current timestamp.
 func Now() interface{} {
 	return time.Now()
@@ -103,16 +112,25 @@ func Now() interface{} {
 
 // GetDefault parse Default value.
 func (field Field) GetDefault() interface{} {
-	if caller, ok := field.Default.(ValueFunc); ok {
-		return caller()
-	}
-	return field.Default
+	return evalValue(field.Default, Context{Field: field})
 }
 
 // GetOnUpdate parse OnUpdate value.
 func (field Field) GetOnUpdate() interface{} {
-	if caller, ok := field.OnUpdate.(ValueFunc); ok {
+	return evalValue(field.OnUpdate, Context{Field: field})
+}
+
+// Get the value of ValueFunc or ContextValueFunc.
+func evalValue(value interface{}, c Context) interface{} {
+	switch caller := value.(type) {
+	case ValueFunc:
+		return caller()
+	case func() interface{}:
 		return caller()
+	case ContextValueFunc:
+		return caller(c)
+	case func(Context) interface{}:
+		return caller(c)
 	}
-	return field.OnUpdate
+	return value
 }
@@ -0,0 +1,210 @@
+package godac
+
+import (
+	"fmt"
+	"godac/sqlbuilder"
+	"strings"
+)
+
+// IncludeOption is a Select option of eager loading, see Include.
+type IncludeOption []string
+
+// Include return a Select option that loads the related records of relations into each record by the Map key of
+// Relation, it is passed as an argument of Select, e.g. orders.Select(db, selector, Include("customer", "lines.product")).
+// Each relation is loaded by one query, nested relations are separated by dot.
+func Include(paths ...string) IncludeOption {
+	return IncludeOption(paths)
+}
+
+// Split Include options from Select arguments.
+func splitIncludes(args []interface{}) ([]interface{}, []string) {
+	var paths []string
+	var rest []interface{}
+	for _, arg := range args {
+		if option, ok := arg.(IncludeOption); ok {
+			paths = append(paths, option...)
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return rest, paths
+}
+
+// Load the related records of relation paths into rows.
+func (table *Table) include(db DB, rows []Map, paths []string) error {
+	if len(rows) == 0 || len(paths) == 0 {
+		return nil
+	}
+	var names []string
+	subPaths := map[string][]string{}
+	for _, path := range paths {
+		parts := strings.SplitN(path, ".", 2)
+		if _, ok := subPaths[parts[0]]; !ok {
+			names = append(names, parts[0])
+			subPaths[parts[0]] = nil
+		}
+		if len(parts) > 1 {
+			subPaths[parts[0]] = append(subPaths[parts[0]], parts[1])
+		}
+	}
+	for _, name := range names {
+		rel, err := table.Relation(name)
+		if err != nil {
+			return err
+		}
+		related, err := rel.load(table, db, rows)
+		if err != nil {
+			return err
+		}
+		if err := rel.Table.include(db, related, subPaths[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the key text and values of columns in row, ok is false if one of the values is NULL.
+func rowKey(row Map, keys []string) (key string, values []interface{}, ok bool) {
+	var list []string
+	for _, k := range keys {
+		value := row[k]
+		if isNull(value) {
+			return "", nil, false
+		}
+		list = append(list, fmt.Sprint(value))
+		values = append(values, value)
+	}
+	return strings.Join(list, "\x00"), values, true
+}
+
+// Get Map keys of columns in table.
+func columnKeys(table *Table, columns []string) []string {
+	keys := make([]string, len(columns))
+	for i, name := range columns {
+		keys[i] = table.keysMap[name]
+	}
+	return keys
+}
+
+// Get condition of columns IN the value lists.
+func whereIn(columns []string, valueLists [][]interface{}) (string, []interface{}) {
+	var args []interface{}
+	if len(columns) == 1 {
+		placeholders := make([]string, len(valueLists))
+		for i, values := range valueLists {
+			placeholders[i] = Placeholder
+			args = append(args, values[0])
+		}
+		return fmt.Sprintf("%s IN (%s)", columns[0], strings.Join(placeholders, sqlbuilder.ColSep)), args
+	}
+	conditions := make([]string, len(valueLists))
+	for i, values := range valueLists {
+		terms := make([]string, len(columns))
+		for j, column := range columns {
+			terms[j] = column + " = " + Placeholder
+			args = append(args, values[j])
+		}
+		conditions[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// Get the Map key of loaded records.
+func (rel Relation) key() string {
+	if rel.Key != "" {
+		return rel.Key
+	}
+	return rel.Name
+}
+
+// Load the related records of rows by one query and set them to rows, return all related records.
+// BelongsTo sets a Map or nil, HasMany and ManyToMany set a []Map.
+func (rel Relation) load(table *Table, db DB, rows []Map) ([]Map, error) {
+	localKeys := columnKeys(table, rel.Columns)
+	var valueLists [][]interface{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if key, values, ok := rowKey(row, localKeys); ok && !seen[key] {
+			seen[key] = true
+			valueLists = append(valueLists, values)
+		}
+	}
+
+	var related []Map
+	var relatedKeys []string
+	if len(valueLists) > 0 {
+		var err error
+		if rel.Kind == RelationManyToMany {
+			related, relatedKeys, err = rel.loadThrough(db, valueLists)
+		} else {
+			condition, args := whereIn(rel.References, valueLists)
+			related, err = rel.Table.Select(db, sqlbuilder.Select().Where(condition), args...)
+			relatedKeys = columnKeys(rel.Table, rel.References)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	groups := map[string][]Map{}
+	for _, item := range related {
+		key, _, _ := rowKey(item, relatedKeys)
+		groups[key] = append(groups[key], item)
+	}
+	if rel.Kind == RelationManyToMany {
+		for _, item := range related {
+			for _, k := range relatedKeys {
+				delete(item, k)
+			}
+		}
+	}
+	for _, row := range rows {
+		key, _, ok := rowKey(row, localKeys)
+		items := groups[key]
+		if !ok {
+			items = nil
+		}
+		if rel.Kind == RelationBelongsTo {
+			if len(items) > 0 {
+				row[rel.key()] = items[0]
+			} else {
+				row[rel.key()] = nil
+			}
+		} else {
+			if items == nil {
+				items = []Map{}
+			}
+			row[rel.key()] = items
+		}
+	}
+	return related, nil
+}
+
+// Load the related records of ManyToMany through the join table,
+// the records include the ThroughColumns values by the returned keys.
+// The join rows referencing missing target records are skipped.
+func (rel Relation) loadThrough(db DB, valueLists [][]interface{}) ([]Map, []string, error) {
+	if err := rel.Through.Open(); err != nil {
+		return nil, nil, err
+	}
+	target, through := rel.Table, rel.Through.Name
+	fields := map[string]Field{}
+	var columns, keys, throughColumns []string
+	for _, name := range target.cols {
+		columns = append(columns, target.Name+"."+name)
+		fields[name] = target.fieldsMap[name]
+	}
+	for i, name := range rel.ThroughColumns {
+		alias := fmt.Sprintf("godac_through_%d", i)
+		columns = append(columns, fmt.Sprintf("%s.%s AS %s", through, name, alias))
+		fields[alias] = Field{Name: alias, Key: alias}
+		keys = append(keys, alias)
+		throughColumns = append(throughColumns, through+"."+name)
+	}
+	condition, args := whereIn(throughColumns, valueLists)
+	query := sqlbuilder.Select().Columns(columns...).From(through).
+		InnerJoin(target.Name, joinOn(target.Name, rel.References, through, rel.ThroughReferences)).
+		Where(condition).SQL()
+	rows, err := mapQuery(false, fields, db, query, args...)
+	return rows, keys, err
+}
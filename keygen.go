@@ -0,0 +1,129 @@
+package godac
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Generator generates key values on INSERT, see Field.Generator.
+type Generator interface {
+	Generate() (interface{}, error)
+}
+
+// GeneratorFunc is an adapter to use function as Generator.
+type GeneratorFunc func() (interface{}, error)
+
+// Generate implements Generator.
+func (f GeneratorFunc) Generate() (interface{}, error) {
+	return f()
+}
+
+// Key generators.
+var (
+	UUIDv4 Generator = GeneratorFunc(newUUIDv4) // Random UUID string.
+	UUIDv7 Generator = GeneratorFunc(newUUIDv7) // Time-ordered UUID string.
+	ULID   Generator = GeneratorFunc(newULID)   // Time-ordered ULID string.
+)
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+func newUUIDv4() (interface{}, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b), nil
+}
+
+// Put 48 bits unix milliseconds in front of b.
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> uint(40-8*i))
+	}
+}
+
+func newUUIDv7() (interface{}, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, err
+	}
+	putMillis(b, time.Now())
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b), nil
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func newULID() (interface{}, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return nil, err
+	}
+	putMillis(b, time.Now())
+	// 128 bits are encoded to 26 chars of 5 bits, the first char has 3 bits only.
+	s := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		bit := (25 - i) * 5
+		var v uint16
+		for j := 0; j < 5; j++ {
+			if n := bit + j; n < 128 && b[15-n/8]>>(uint(n)%8)&1 == 1 {
+				v |= 1 << uint(j)
+			}
+		}
+		s[i] = crockford[v]
+	}
+	return string(s), nil
+}
+
+// SnowflakeEpoch is the epoch of Snowflake IDs.
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake is a Generator of int64 IDs composed of 41 bits milliseconds since SnowflakeEpoch,
+// 10 bits node ID and 12 bits sequence.
+type Snowflake struct {
+	mu   sync.Mutex
+	node int64
+	last int64
+	seq  int64
+}
+
+// NewSnowflake create a Snowflake generator, node must be in 0-1023.
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > 1023 {
+		return nil, fmt.Errorf("Snowflake node %d out of range 0-1023", node)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// Generate implements Generator.
+func (s *Snowflake) Generate() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := int64(time.Since(SnowflakeEpoch) / time.Millisecond)
+	if now < s.last {
+		now = s.last
+	}
+	if now == s.last {
+		s.seq = (s.seq + 1) & 0xfff
+		if s.seq == 0 {
+			for now <= s.last {
+				time.Sleep(100 * time.Microsecond)
+				now = int64(time.Since(SnowflakeEpoch) / time.Millisecond)
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = now
+	return now<<22 | s.node<<12 | s.seq, nil
+}
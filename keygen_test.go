@@ -0,0 +1,42 @@
+package godac
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestKeyGenerators(t *testing.T) {
+	v4, _ := UUIDv4.Generate()
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(v4.(string)) {
+		t.Errorf("UUIDv4 = %s", v4)
+	}
+	v7a, _ := UUIDv7.Generate()
+	time.Sleep(2 * time.Millisecond)
+	v7b, _ := UUIDv7.Generate()
+	if v7a.(string)[14] != '7' || v7a.(string) >= v7b.(string) {
+		t.Errorf("UUIDv7 = %s, %s", v7a, v7b)
+	}
+	ulidA, _ := ULID.Generate()
+	time.Sleep(2 * time.Millisecond)
+	ulidB, _ := ULID.Generate()
+	if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(ulidA.(string)) || ulidA.(string) >= ulidB.(string) {
+		t.Errorf("ULID = %s, %s", ulidA, ulidB)
+	}
+
+	if _, err := NewSnowflake(1024); err == nil {
+		t.Error("NewSnowflake(1024) should fail")
+	}
+	sf, _ := NewSnowflake(5)
+	var last int64
+	for i := 0; i < 10000; i++ {
+		id, _ := sf.Generate()
+		if id.(int64) <= last {
+			t.Fatalf("Snowflake %d not greater than %d", id, last)
+		}
+		if node := id.(int64) >> 12 & 0x3ff; node != 5 {
+			t.Fatalf("Snowflake node = %d", node)
+		}
+		last = id.(int64)
+	}
+}
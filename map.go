@@ -2,101 +2,158 @@ package godac
 
 import (
 	"database/sql"
+	"fmt"
 )
 
 // MapQuery fetching rows to []Map. keys define the Map key of columns.
 func MapQuery(keys map[string]string, db DB, query string, args ...interface{}) ([]Map, error) {
-	return mapQuery(false, keys, db, query, args...)
+	return mapQuery(false, keyFields(keys), db, query, args...)
 }
 
 // MapQueryRow fetching first row to Map. keys define the Map key of columns.
 func MapQueryRow(keys map[string]string, db DB, query string, args ...interface{}) (Map, error) {
-	maps, err := mapQuery(true, keys, db, query, args...)
+	maps, err := mapQuery(true, keyFields(keys), db, query, args...)
 	if err != nil || len(maps) == 0 {
 		return nil, err
 	}
 	return maps[0], nil
 }
 
+// Column is the metadata of a Tabular column.
+type Column struct {
+	Key      string `json:"key"`
+	Title    string `json:"title"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// Tabular is a compact query result, each row is a value array in the order of Columns.
+type Tabular struct {
+	Columns []Column        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// TabularQuery fetching rows to Tabular. fields define the Field of columns by column name.
+func TabularQuery(fields map[string]Field, db DB, query string, args ...interface{}) (*Tabular, error) {
+	result := &Tabular{Columns: []Column{}, Rows: [][]interface{}{}}
+	columns := func(colTypes []*sql.ColumnType) {
+		for _, col := range colTypes {
+			field := columnField(fields, col.Name())
+			nullable, _ := col.Nullable()
+			result.Columns = append(result.Columns, Column{field.GetKey(), field.GetTitle(), col.DatabaseTypeName(), nullable})
+		}
+	}
+	err := queryRows(fields, db, query, args, columns, func(values []interface{}) bool {
+		result.Rows = append(result.Rows, values)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Convert Map keys of columns to fields.
+func keyFields(keys map[string]string) map[string]Field {
+	fields := make(map[string]Field, len(keys))
+	for name, key := range keys {
+		fields[name] = Field{Name: name, Key: key}
+	}
+	return fields
+}
+
+// Get the field of column, a field only with Name if it is undefined.
+func columnField(fields map[string]Field, name string) Field {
+	if field, ok := fields[name]; ok {
+		return field
+	}
+	return Field{Name: name}
+}
+
 // Set firstOnly is true to return the first row only.
-func mapQuery(firstOnly bool, keys map[string]string, db DB, query string, args ...interface{}) ([]Map, error) {
-	rows, err := db.Query(query, args...)
+func mapQuery(firstOnly bool, fields map[string]Field, db DB, query string, args ...interface{}) ([]Map, error) {
+	var result []Map
+	var keys []string
+	columns := func(colTypes []*sql.ColumnType) {
+		for _, col := range colTypes {
+			keys = append(keys, columnField(fields, col.Name()).GetKey())
+		}
+	}
+	err := queryRows(fields, db, query, args, columns, func(values []interface{}) bool {
+		row := make(Map)
+		for i, value := range values {
+			if value != nil {
+				row[keys[i]] = value
+			}
+		}
+		result = append(result, row)
+		return !firstOnly
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+// queryRows calls columns with the column types first, then calls row for each row until it returns false.
+// The NULL value of row is nil, the other values are converted by type mappings.
+func queryRows(fields map[string]Field, db DB, query string, args []interface{}, columns func([]*sql.ColumnType), row func([]interface{}) bool) error {
+	rows, err := dbQuery(db, query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
 	colTypes, err := rows.ColumnTypes()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	columns(colTypes)
 
-	var result []Map
-	dest := makeScanDest(colTypes)
+	mappings := columnMappings(fields, colTypes)
+	colFields := make([]Field, len(colTypes))
+	for i, col := range colTypes {
+		colFields[i] = columnField(fields, col.Name())
+	}
+	dest := make([]interface{}, len(colTypes))
+	for i := range dest {
+		dest[i] = new(interface{})
+	}
 	for rows.Next() {
 		if err := rows.Scan(dest...); err != nil {
-			return nil, err
+			return err
 		}
-		row := make(Map)
+		values := make([]interface{}, len(dest))
 		for i := range dest {
-			name := colTypes[i].Name()
-			key := keys[name]
-			if key == "" {
-				key = convertName(name)
-			}
-			value := dest[i]
-			switch value.(type) {
-			case *sql.NullInt64:
-				if v := value.(*sql.NullInt64); v.Valid {
-					row[key] = v.Int64
-				}
-			case *sql.NullFloat64:
-				if v := value.(*sql.NullFloat64); v.Valid {
-					row[key] = v.Float64
+			value := *dest[i].(*interface{})
+			if value != nil && mappings[i] != nil {
+				if value, err = mappings[i](value, DefaultDialect); err != nil {
+					return fmt.Errorf("column %s: %v", colTypes[i].Name(), err)
 				}
-			case *sql.NullBool:
-				if v := value.(*sql.NullBool); v.Valid {
-					row[key] = v.Bool
-				}
-			case *sql.NullString:
-				if v := value.(*sql.NullString); v.Valid {
-					row[key] = v.String
-				}
-			default:
-				row[key] = *value.(*interface{})
 			}
+			if value, err = colFields[i].decode(value); err != nil {
+				return fmt.Errorf("column %s: %v", colTypes[i].Name(), err)
+			}
+			values[i] = value
 		}
-		result = append(result, row)
-		if firstOnly {
+		if !row(values) {
 			break
 		}
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return result, nil
+	return rows.Err()
 }
 
-// 如果用 interface{} 做为目标，驱动端返回的是 []byte，无法判断具体类型；
-// 如果用 int32, int64, string 等做为目标，如果有的列为 NULL 值，则会报错；
-// VARCHAR 和 BINARY 列的 ScanType 都是 sql.RawBytes，所以不能用 ScanType 来判断。
-func makeScanDest(colTypes []*sql.ColumnType) []interface{} {
-	dest := make([]interface{}, len(colTypes))
+// 以 interface{} 做为扫描目标，驱动端返回的可能是 []byte, int64, time.Time 等，
+// 再按 Field.Mapping 或者当前 Dialect 中数据库类型名对应的 TypeMapping 转换为 Go 值；
+// 没有对应 TypeMapping 的列保留驱动端返回的值。
+func columnMappings(fields map[string]Field, colTypes []*sql.ColumnType) []TypeMapping {
+	mappings := make([]TypeMapping, len(colTypes))
 	for i, col := range colTypes {
-		//fmt.Println(col.Name(), col.DatabaseTypeName(), col.ScanType())
-		switch col.DatabaseTypeName() {
-		case "INT", "TINYINT", "SMALLINT", "BIGINT":
-			dest[i] = new(sql.NullInt64)
-		case "FLOAT", "DOUBLE", "REAL":
-			dest[i] = new(sql.NullFloat64)
-		case "BOOL", "BOOLEAN":
-			dest[i] = new(sql.NullBool)
-		case "VARCHAR", "CHAR", "TEXT", "JSON", "DECIMAL",
-			"DATETIME", "DATE", "TIME":
-			dest[i] = new(sql.NullString)
-		default:
-			dest[i] = new(interface{})
+		if field, ok := fields[col.Name()]; ok && field.Mapping != nil {
+			mappings[i] = field.Mapping
+		} else {
+			mappings[i] = DefaultDialect.mapping(col)
 		}
 	}
-	return dest
+	return mappings
 }
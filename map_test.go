@@ -2,8 +2,11 @@ package godac
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"godac/sqlbuilder"
 	"log"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -32,3 +35,32 @@ func TestMapQuery(t *testing.T) {
 
 	t.Logf("\n%s\n", js)
 }
+
+func TestTabularQuery(t *testing.T) {
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"id", "user_name", "note"}, [][]driver.Value{{int64(1), "jo", nil}, {int64(2), "al", "hi"}}
+	})
+	fake.types = map[string]fakeColumn{"id": {"BIGINT", false}, "user_name": {"VARCHAR", false}, "note": {"TEXT", true}}
+	users := &Table{Name: "users", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "user_name", Title: "Name"}, {Name: "note"}}}
+	tabular, err := users.SelectTabular(db, sqlbuilder.Select().Where("id > ?"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantColumns := []Column{{"id", "ID", "BIGINT", false}, {"userName", "Name", "VARCHAR", false}, {"note", "Note", "TEXT", true}}
+	if !reflect.DeepEqual(tabular.Columns, wantColumns) {
+		t.Errorf("columns = %v", tabular.Columns)
+	}
+	wantRows := [][]interface{}{{int64(1), "jo", nil}, {int64(2), "al", "hi"}}
+	if !reflect.DeepEqual(tabular.Rows, wantRows) {
+		t.Errorf("rows = %#v", tabular.Rows)
+	}
+
+	query := &Query{Selector: sqlbuilder.Select().From("users"), Fields: users.Fields}
+	if _, err := query.SelectTabular(db, sqlbuilder.Select().Where("id > ?"), 0); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SELECT id, user_name, note FROM users WHERE id > ? [0]", "SELECT * FROM users WHERE id > ? [0]"}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
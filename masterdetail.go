@@ -0,0 +1,453 @@
+package godac
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"godac/sqlbuilder"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// MasterDetail is a DataSet of the master table and its detail records of HasMany relations,
+// the detail records are nested in the master record as []Map by the Map key of relation.
+// Insert, Update and Delete save the master and detail records in one transaction if db can begin it,
+// the validation errors of master and detail records are returned together in a ValidationError,
+// by key like "customerID" and "lines.0.qty".
+type MasterDetail struct {
+	Master  *Table
+	Details []string // Names of HasMany relations of Master.
+}
+
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Run fn in a transaction if db can begin it, otherwise fn runs with db.
+func withTx(db DB, fn func(DB) error) error {
+	ctx, inner := context.Background(), db
+	if c, ok := db.(*ctxDB); ok {
+		ctx, inner = c.ctx, c.db
+	}
+	beginner, ok := inner.(txBeginner)
+	if !ok {
+		return fn(db)
+	}
+	tx, err := beginner.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(WithContext(ctx, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get the relations of details.
+func (md *MasterDetail) relations() ([]Relation, error) {
+	var relations []Relation
+	for _, name := range md.Details {
+		rel, err := md.Master.Relation(name)
+		if err != nil {
+			return nil, err
+		}
+		if rel.Kind != RelationHasMany {
+			return nil, fmt.Errorf("Relation %s.%s is not HasMany", md.Master.Name, name)
+		}
+		relations = append(relations, rel)
+	}
+	return relations, nil
+}
+
+// Select query the master records with detail records.
+func (md *MasterDetail) Select(db DB, selector sqlbuilder.Selector, args ...interface{}) ([]Map, error) {
+	return md.Master.Select(db, selector, append(args, Include(md.Details...))...)
+}
+
+// Get query the master record with detail records by primary key, return ErrNotFound if it does not exist.
+func (md *MasterDetail) Get(db DB, key Map) (Map, error) {
+	query, args, err := md.Master.WherePrimaryKey(false, false, key)
+	if err != nil {
+		return nil, err
+	}
+	return getRecord(md, db, sqlbuilder.Select().Where(query), args...)
+}
+
+// ExistsKey check the master record of primary key in key exists.
+func (md *MasterDetail) ExistsKey(db DB, key Map) (bool, error) {
+	return md.Master.ExistsKey(db, key)
+}
+
+// Reload requery the master record with detail records, return a copy of record with the values in database.
+func (md *MasterDetail) Reload(db DB, record Map) (Map, error) {
+	return reloadRecord(md, db, record)
+}
+
+// Count query SELECT COUNT(*) of master records.
+func (md *MasterDetail) Count(db DB, selector sqlbuilder.Selector, args ...interface{}) (int64, error) {
+	return md.Master.Count(db, selector, args...)
+}
+
+// Page query a page of master records with detail records, page starts from 1.
+func (md *MasterDetail) Page(db DB, selector sqlbuilder.Selector, page, size int64, args ...interface{}) (*Paged, error) {
+	return selectPage(md, db, selector, page, size, args...)
+}
+
+// Split the master record and detail records, ok is false if the details of relation are absent.
+func splitDetails(record Map, rel Relation) (rows []Map, ok bool, err error) {
+	value, exist := record[rel.key()]
+	if !exist || isNull(value) {
+		return nil, false, nil
+	}
+	switch v := value.(type) {
+	case []Map:
+		return v, true, nil
+	case []interface{}:
+		for i, item := range v {
+			switch row := item.(type) {
+			case Map:
+				rows = append(rows, row)
+			case map[string]interface{}:
+				for k, v := range row {
+					if v == nil {
+						row[k] = Null
+					}
+				}
+				rows = append(rows, Map(row))
+			default:
+				return nil, false, fmt.Errorf("%s[%d]: record must be an object", rel.key(), i)
+			}
+		}
+		return rows, true, nil
+	}
+	return nil, false, fmt.Errorf("%s: records must be an array", rel.key())
+}
+
+// Get the master record without detail records.
+func (md *MasterDetail) masterRecord(record Map, relations []Relation) Map {
+	rec := Map{}
+	for k, v := range record {
+		rec[k] = v
+	}
+	for _, rel := range relations {
+		delete(rec, rel.key())
+	}
+	return rec
+}
+
+// Set the referencing columns of detail record by master record.
+func setForeignKey(master *Table, rel Relation, masterRecord, row Map) {
+	for i, column := range rel.Columns {
+		row[rel.Table.keysMap[rel.References[i]]] = masterRecord[master.keysMap[column]]
+	}
+}
+
+// Add the validation errors of detail row with key prefix, the other errors are returned.
+func addRowErrors(errs ValidationError, prefix string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e, ok := err.(ValidationError)
+	if !ok {
+		return err
+	}
+	for key, fe := range e {
+		errs[prefix+"."+key] = fe
+	}
+	return nil
+}
+
+// Insert the master record, then insert its detail records with the referencing columns of master key.
+func (md *MasterDetail) Insert(db DB, record Map) (Result, error) {
+	relations, err := md.relations()
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	var saved Map
+	err = withTx(db, func(tx DB) error {
+		errs := ValidationError{}
+		rst, err := md.Master.Insert(tx, md.masterRecord(record, relations))
+		if err = errs.merge(err); err != nil {
+			return err
+		}
+		if rst == nil {
+			saved = md.masterRecord(record, relations)
+		} else if saved, err = rst.Record(true); err != nil {
+			return err
+		} else if saved == nil {
+			return ErrNotFound
+		}
+		for _, rel := range relations {
+			rows, ok, err := splitDetails(record, rel)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			var savedRows []Map
+			for i, row := range rows {
+				row = copyMap(row)
+				setForeignKey(md.Master, rel, saved, row)
+				prefix := fmt.Sprintf("%s.%d", rel.key(), i)
+				if rst == nil {
+					if err = addRowErrors(errs, prefix, validateDetail(tx, rel, row)); err != nil {
+						return err
+					}
+					continue
+				}
+				savedRow, err := insertDetail(tx, rel.Table, row)
+				if err = addRowErrors(errs, prefix, err); err != nil {
+					return err
+				}
+				savedRows = append(savedRows, savedRow)
+			}
+			saved[rel.key()] = savedRows
+		}
+		result = masterDetailResult{rst, saved}
+		return errs.orNil()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Validate the detail record of INSERT without executing it, it is used when the master record is invalid.
+// The referencing columns are not validated since the master key is unknown.
+func validateDetail(db DB, rel Relation, row Map) error {
+	_, _, _, err := rel.Table.insertStatement(Context{StateInsert, db, rel.Table, rel.Table, row, Field{}})
+	if errs, ok := err.(ValidationError); ok {
+		for _, column := range rel.References {
+			delete(errs, rel.Table.keysMap[column])
+		}
+		return errs.orNil()
+	}
+	return err
+}
+
+// Insert the detail record, return the final record with the generated key.
+func insertDetail(db DB, table *Table, row Map) (Map, error) {
+	rst, err := table.Insert(db, row)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := rst.Record(true)
+	if err == nil && saved == nil {
+		err = ErrNotFound
+	}
+	return saved, err
+}
+
+// Update the master record, then synchronize its detail records of relations present in record:
+// the records matching existing primary key are updated, the others are inserted,
+// and the existing records absent in record are deleted.
+func (md *MasterDetail) Update(db DB, record Map) (Result, error) {
+	relations, err := md.relations()
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	err = withTx(db, func(tx DB) error {
+		master, err := md.Master.Get(tx, record)
+		if err != nil {
+			return err
+		}
+		rec := md.masterRecord(record, relations)
+		var rst Result
+		errs := ValidationError{}
+		if md.hasUpdates(rec) {
+			rst, err = md.Master.Update(tx, rec)
+			if err = errs.merge(err); err != nil {
+				return err
+			}
+			if rst != nil {
+				if rec, err = rst.Record(false); err != nil {
+					return err
+				}
+			}
+		}
+		for k, v := range rec {
+			master[k] = v
+		}
+		for _, rel := range relations {
+			rows, ok, err := splitDetails(record, rel)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				delete(master, rel.key())
+				continue
+			}
+			savedRows, err := md.syncDetails(tx, rel, master, rows, errs)
+			if err != nil {
+				return err
+			}
+			master[rel.key()] = savedRows
+		}
+		result = masterDetailResult{rst, master}
+		return errs.orNil()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Check the master record has fields to update besides primary key.
+func (md *MasterDetail) hasUpdates(record Map) bool {
+	for i, field := range md.Master.Fields {
+		if field.PrimaryKey || field.AutoInc {
+			continue
+		}
+		if _, exist := record[md.Master.keys[i]]; exist || field.OnUpdate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert, update or delete the detail records of master record.
+func (md *MasterDetail) syncDetails(tx DB, rel Relation, master Map, rows []Map, errs ValidationError) ([]Map, error) {
+	detail := rel.Table
+	query, args, err := rel.where(md.Master, master)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := detail.Select(tx, sqlbuilder.Select().Where(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	existingKeys := map[string]bool{}
+	for _, row := range existing {
+		if key, ok := detailKey(detail, row); ok {
+			existingKeys[key] = true
+		}
+	}
+	postedKeys := map[string]bool{}
+	var savedRows []Map
+	for i, row := range rows {
+		row = copyMap(row)
+		setForeignKey(md.Master, rel, master, row)
+		var saved Map
+		if key, ok := detailKey(detail, row); ok && existingKeys[key] {
+			postedKeys[key] = true
+			var rst Result
+			if rst, err = detail.Update(tx, row); err == nil {
+				saved, err = rst.Record(false)
+			}
+		} else {
+			saved, err = insertDetail(tx, detail, row)
+		}
+		if err = addRowErrors(errs, fmt.Sprintf("%s.%d", rel.key(), i), err); err != nil {
+			return nil, err
+		}
+		savedRows = append(savedRows, saved)
+	}
+	for _, row := range existing {
+		if key, ok := detailKey(detail, row); ok && !postedKeys[key] {
+			if _, err := detail.Delete(tx, row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return savedRows, nil
+}
+
+// Get the key text of primary key values in detail row, the values are coerced to the field types
+// and integral numbers are compared as int64, e.g. 1e+06 of JSON matches 1000000 of database.
+// ok is false if one of the values is NULL or invalid.
+func detailKey(table *Table, row Map) (string, bool) {
+	var list []string
+	for _, i := range table.primaryKey {
+		value, err := table.Fields[i].Coerce(row[table.keys[i]])
+		if err != nil || isNull(value) {
+			return "", false
+		}
+		list = append(list, fmt.Sprint(keyValue(value)))
+	}
+	return strings.Join(list, "\x00"), len(list) > 0
+}
+
+// Normalize the primary key value for comparison.
+func keyValue(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+			return int64(f)
+		}
+	case reflect.Slice:
+		if b, ok := value.([]byte); ok {
+			return string(b)
+		}
+	}
+	return value
+}
+
+// Delete the detail records by Delete of detail tables, then delete the master record.
+func (md *MasterDetail) Delete(db DB, record Map) (Result, error) {
+	relations, err := md.relations()
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	err = withTx(db, func(tx DB) error {
+		for _, rel := range relations {
+			query, args, err := rel.where(md.Master, record)
+			if err != nil {
+				return err
+			}
+			if err := cascadeDelete(tx, rel.Table, query, args); err != nil {
+				return err
+			}
+		}
+		result, err = md.Master.Delete(tx, md.masterRecord(record, relations))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func copyMap(m Map) Map {
+	result := Map{}
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+// The result of MasterDetail, the record includes the saved detail records.
+type masterDetailResult struct {
+	master Result
+	record Map
+}
+
+func (r masterDetailResult) Record(refresh bool) (Map, error) {
+	return r.record, nil
+}
+
+func (r masterDetailResult) LastInsertId() (int64, error) {
+	if r.master == nil {
+		return 0, fmt.Errorf("LastInsertId is not available")
+	}
+	return r.master.LastInsertId()
+}
+
+func (r masterDetailResult) RowsAffected() (int64, error) {
+	if r.master == nil {
+		return 0, nil
+	}
+	return r.master.RowsAffected()
+}
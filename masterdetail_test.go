@@ -0,0 +1,214 @@
+package godac
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func TestSplitDetails(t *testing.T) {
+	var record Map
+	if err := json.Unmarshal([]byte(`{"id":1,"lines":[{"qty":2},{"qty":3}]}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	md := &MasterDetail{Master: testOrders, Details: []string{"lines"}}
+	relations, err := md.relations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, ok, err := splitDetails(record, relations[0])
+	if err != nil || !ok || len(rows) != 2 || rows[1]["qty"] != float64(3) {
+		t.Fatal(rows, ok, err)
+	}
+	if master := md.masterRecord(record, relations); len(master) != 1 {
+		t.Fatal(master)
+	}
+	if _, ok, _ := splitDetails(Map{"id": 1}, relations[0]); ok {
+		t.Fatal("absent details should not be synchronized")
+	}
+	if _, _, err := splitDetails(Map{"lines": "x"}, relations[0]); err == nil {
+		t.Fatal("expected error")
+	}
+
+	row := Map{"id": int64(7)}
+	setForeignKey(testOrders, relations[0], Map{"id": int64(1)}, row)
+	if row["orderID"] != int64(1) {
+		t.Fatal(row)
+	}
+
+	if _, err := (&MasterDetail{Master: testOrders, Details: []string{"customer"}}).relations(); err == nil {
+		t.Fatal("expected error of BelongsTo relation")
+	}
+}
+
+func TestRowErrors(t *testing.T) {
+	errs := ValidationError{}
+	rowErr := ValidationError{}
+	rowErr.Add("qty", Field{Name: "qty"}, ErrRequireAny)
+	if err := addRowErrors(errs, "lines.2", rowErr); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := errs["lines.2.qty"]; !ok {
+		t.Fatal(errs)
+	}
+	if err := addRowErrors(errs, "lines.3", ErrNotFound); err != ErrNotFound {
+		t.Fatal(err)
+	}
+}
+
+func newTestInvoices() *MasterDetail {
+	lines := &Table{Name: "invoice_lines", Fields: []Field{
+		{Name: "id", PrimaryKey: true, AutoInc: true}, {Name: "invoice_id"}, {Name: "qty", Validations: []validation.Rule{validation.Required}},
+	}}
+	notes := &Table{Name: "invoice_notes", Fields: []Field{{Name: "id", PrimaryKey: true, AutoInc: true}, {Name: "invoice_id"}}}
+	invoices := &Table{Name: "invoices", Fields: []Field{{Name: "id", PrimaryKey: true, AutoInc: true},
+		{Name: "customer_id", Validations: []validation.Rule{validation.Required}}},
+		Relations: []Relation{HasMany("lines", lines, "invoice_id"), HasMany("notes", notes, "invoice_id")}}
+	return &MasterDetail{Master: invoices, Details: []string{"notes", "lines"}}
+}
+
+func TestMasterDetailInsert(t *testing.T) {
+	var found bool
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if found && strings.HasPrefix(query, "SELECT id, customer_id FROM invoices") {
+			return []string{"id", "customer_id"}, [][]driver.Value{{args[0], int64(9)}}
+		}
+		if strings.HasPrefix(query, "SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ?") {
+			return []string{"id", "invoice_id", "qty"}, [][]driver.Value{{args[0], int64(1), args[0]}}
+		}
+		return nil, nil
+	})
+	md := newTestInvoices()
+	var record Map
+	if err := json.Unmarshal([]byte(`{"customerID":9,"lines":[{"qty":2},{"qty":3}]}`), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	found = true
+	result, err := md.Insert(db, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := result.Record(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, _ := saved["lines"].([]Map)
+	if len(lines) != 2 || lines[0]["id"] != int64(2) || lines[1]["id"] != int64(3) || lines[1]["invoiceID"] != int64(1) {
+		t.Errorf("record = %v", saved)
+	}
+	want := []string{
+		"BEGIN",
+		"INSERT INTO invoices(customer_id)VALUES(?) [9]",
+		"SELECT id, customer_id FROM invoices WHERE id = ? [1]",
+		"INSERT INTO invoice_lines(invoice_id, qty)VALUES(?, ?) [1 2]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ? [2]",
+		"INSERT INTO invoice_lines(invoice_id, qty)VALUES(?, ?) [1 3]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ? [3]",
+		"COMMIT",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+
+	record["lines"] = []interface{}{map[string]interface{}{"qty": 2}, map[string]interface{}{"qty": nil}}
+	_, err = md.Insert(db, record)
+	errs, ok := err.(ValidationError)
+	if _, exist := errs["lines.1.qty"]; !ok || !exist || len(errs) != 1 {
+		t.Errorf("validation error = %v", err)
+	}
+
+	found = false
+	if _, err := md.Insert(db, record); err != ErrNotFound {
+		t.Errorf("not found error = %v", err)
+	}
+	if got := fake.statements(); got[len(got)-1] != "ROLLBACK" {
+		t.Errorf("last statement = %q", got[len(got)-1])
+	}
+}
+
+func TestMasterDetailUpdate(t *testing.T) {
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.HasPrefix(query, "SELECT id, customer_id FROM invoices"):
+			return []string{"id", "customer_id"}, [][]driver.Value{{int64(1), int64(9)}}
+		case strings.HasPrefix(query, "SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ?"):
+			return []string{"id", "invoice_id", "qty"}, [][]driver.Value{{args[0], int64(1), int64(1)}}
+		case strings.HasPrefix(query, "SELECT id, invoice_id, qty FROM invoice_lines"):
+			return []string{"id", "invoice_id", "qty"}, [][]driver.Value{
+				{int64(1000000), int64(1), int64(2)}, {int64(5), int64(1), int64(3)},
+			}
+		}
+		return nil, nil
+	})
+	var record Map
+	if err := json.Unmarshal([]byte(`{"id":1,"lines":[{"id":1000000,"qty":4},{"qty":1}]}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	result, err := newTestInvoices().Update(db, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := result.Record(false)
+	if lines, _ := saved["lines"].([]Map); len(lines) != 2 || lines[1]["id"] != int64(1) {
+		t.Errorf("record = %v", saved)
+	}
+	want := []string{
+		"BEGIN",
+		"SELECT id, customer_id FROM invoices WHERE id = ? [1]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE invoice_id = ? [1]",
+		"UPDATE invoice_lines SET invoice_id = ?, qty = ? WHERE id = ? [1 4 1e+06]",
+		"INSERT INTO invoice_lines(invoice_id, qty)VALUES(?, ?) [1 1]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ? [1]",
+		"DELETE FROM invoice_lines WHERE id = ? [5]",
+		"COMMIT",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestMasterDetailErrors(t *testing.T) {
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SELECT id, customer_id FROM invoices") {
+			return []string{"id", "customer_id"}, [][]driver.Value{{int64(1), int64(9)}}
+		}
+		if strings.HasPrefix(query, "SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ?") {
+			return []string{"id", "invoice_id", "qty"}, [][]driver.Value{{args[0], int64(1), int64(2)}}
+		}
+		return nil, nil
+	})
+	md := newTestInvoices()
+	var record Map
+	if err := json.Unmarshal([]byte(`{"customerID":null,"lines":[{"qty":null},{"qty":2}]}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	_, err := md.Insert(db, record)
+	errs, ok := err.(ValidationError)
+	if !ok || errs.Error() != "Customer ID: cannot be blank; Qty: cannot be blank" || len(errs["lines.0.qty"].Code) == 0 {
+		t.Errorf("insert error = %v", err)
+	}
+
+	record["id"] = int64(1)
+	_, err = md.Update(db, record)
+	errs, ok = err.(ValidationError)
+	if _, exist := errs["lines.0.qty"]; !ok || !exist || len(errs) != 2 {
+		t.Errorf("update error = %v", err)
+	}
+	want := []string{
+		"BEGIN", "ROLLBACK",
+		"BEGIN",
+		"SELECT id, customer_id FROM invoices WHERE id = ? [1]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE invoice_id = ? [1]",
+		"INSERT INTO invoice_lines(invoice_id, qty)VALUES(?, ?) [1 2]",
+		"SELECT id, invoice_id, qty FROM invoice_lines WHERE id = ? [1]",
+		"ROLLBACK",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
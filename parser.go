@@ -0,0 +1,210 @@
+package godac
+
+import (
+	"fmt"
+	"godac/sqlbuilder"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Query parameter errors.
+var (
+	ErrFilterField    = validation.NewError("filter_field", "is not filterable")
+	ErrFilterOperator = validation.NewError("filter_operator", "unsupported filter operator")
+	ErrSortField      = validation.NewError("sort_field", "is not sortable")
+	ErrPageParam      = validation.NewError("page_param", "must be a positive integer")
+)
+
+// Filter operators of QueryParser.
+var filterOperators = map[string]string{
+	"eq": "=", "ne": "<>", "lt": "<", "le": "<=", "gt": ">", "ge": ">=", "like": "LIKE", "in": "IN", "null": "IS NULL",
+}
+
+var filterParam = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([a-z]+)\])?$`)
+
+// QueryParser parses the filter, sort and page parameters of HTTP query to a Selector, e.g.
+// ?filter[name]=abc&filter[age][gt]=18&filter[id][in]=1,2&filter[deleted][null]=true&sort=-created,name&page=2&size=20.
+// The supported filter operators are eq, ne, lt, le, gt, ge, like, in and null. The client keys are Field.GetKey.
+type QueryParser struct {
+	Fields      []Field
+	Filterable  []string // Keys of filterable fields, nil means all fields.
+	Sortable    []string // Keys of sortable fields, nil means all fields.
+	DefaultSort string   // Default sort parameter, e.g. "-created".
+	PageSize    int64    // Default page size, default is 20.
+	MaxPageSize int64    // Max page size, default is 100.
+}
+
+// ListQuery is the parsed result of QueryParser.
+type ListQuery struct {
+	Selector sqlbuilder.Selector
+	Args     []interface{}
+	Page     int64
+	Size     int64
+}
+
+// Find the field of key in allowed keys.
+func (parser QueryParser) field(key string, allowed []string) (Field, bool) {
+	if allowed != nil {
+		found := false
+		for _, v := range allowed {
+			if v == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Field{}, false
+		}
+	}
+	for _, field := range parser.Fields {
+		if field.GetKey() == key {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// Parse query parameters. The errors of parameters are returned as an Error with code 400.
+func (parser QueryParser) Parse(values url.Values) (*ListQuery, error) {
+	result := &ListQuery{Selector: sqlbuilder.Select(), Page: 1, Size: parser.PageSize}
+	if result.Size <= 0 {
+		result.Size = 20
+	}
+	var errs []string
+	addError := func(field Field, err error) {
+		errs = append(errs, fieldError(field, err).Error())
+	}
+	unknown := func(key string) Field {
+		return Field{Name: key, Title: key}
+	}
+
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		match := filterParam.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		field, ok := parser.field(match[1], parser.Filterable)
+		if !ok {
+			addError(unknown(match[1]), ErrFilterField)
+			continue
+		}
+		op := match[2]
+		if op == "" {
+			op = "eq"
+		}
+		operator, ok := filterOperators[op]
+		if !ok {
+			addError(field, ErrFilterOperator)
+			continue
+		}
+		for _, text := range values[name] {
+			condition, args, err := filterCondition(field, op, operator, text)
+			if err != nil {
+				addError(field, err)
+				continue
+			}
+			result.Selector = result.Selector.WhereAnd(condition)
+			result.Args = append(result.Args, args...)
+		}
+	}
+
+	sortParam := values.Get("sort")
+	if sortParam == "" {
+		sortParam = parser.DefaultSort
+	}
+	var orderBy []string
+	for _, item := range strings.Split(sortParam, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		desc := strings.HasPrefix(item, "-")
+		key := strings.TrimPrefix(strings.TrimPrefix(item, "-"), "+")
+		field, ok := parser.field(key, parser.Sortable)
+		if !ok {
+			addError(unknown(key), ErrSortField)
+			continue
+		}
+		if desc {
+			orderBy = append(orderBy, field.Name+" DESC")
+		} else {
+			orderBy = append(orderBy, field.Name)
+		}
+	}
+	if len(orderBy) > 0 {
+		result.Selector = result.Selector.OrderBy(strings.Join(orderBy, sqlbuilder.ColSep))
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int64
+	}{{"page", &result.Page}, {"size", &result.Size}} {
+		if text := values.Get(param.name); text != "" {
+			n, err := strconv.ParseInt(text, 10, 64)
+			if err != nil || n < 1 {
+				addError(unknown(param.name), ErrPageParam)
+				continue
+			}
+			*param.value = n
+		}
+	}
+	maxSize := parser.MaxPageSize
+	if maxSize <= 0 {
+		maxSize = 100
+	}
+	if result.Size > maxSize {
+		result.Size = maxSize
+	}
+
+	if len(errs) > 0 {
+		return nil, NewError(strings.Join(errs, "; "), 400)
+	}
+	return result, nil
+}
+
+// Get the parameterized condition of a filter.
+func filterCondition(field Field, op, operator, text string) (string, []interface{}, error) {
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(text)
+		if err != nil {
+			return "", nil, ErrCoerceBool
+		}
+		if isNull {
+			return field.Name + " IS NULL", nil, nil
+		}
+		return field.Name + " IS NOT NULL", nil, nil
+	case "like":
+		return field.Name + " LIKE ?", []interface{}{text}, nil
+	case "in":
+		var args []interface{}
+		var placeholders []string
+		for _, item := range strings.Split(text, ",") {
+			value, err := field.Coerce(item)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, value)
+			placeholders = append(placeholders, Placeholder)
+		}
+		return fmt.Sprintf("%s IN (%s)", field.Name, strings.Join(placeholders, sqlbuilder.ColSep)), args, nil
+	}
+	value, err := field.Coerce(text)
+	if err != nil {
+		return "", nil, err
+	}
+	if value == Null {
+		return "", nil, validation.ErrRequired
+	}
+	return fmt.Sprintf("%s %s ?", field.Name, operator), []interface{}{value}, nil
+}
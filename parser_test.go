@@ -0,0 +1,44 @@
+package godac
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryParser(t *testing.T) {
+	parser := QueryParser{
+		Fields:   []Field{{Name: "id", Type: TypeInt}, {Name: "user_name"}, {Name: "created_at"}, {Name: "deleted_at"}},
+		Sortable: []string{"createdAt", "id"},
+	}
+	values, _ := url.ParseQuery("filter[id][in]=1,2&filter[userName][like]=a%25&filter[deletedAt][null]=true&sort=-createdAt,id&page=2&size=500")
+	result, err := parser.Parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * WHERE deleted_at IS NULL AND id IN (?, ?) AND user_name LIKE ? ORDER BY created_at DESC, id"
+	if sql := result.Selector.SQL(); sql != want {
+		t.Errorf("SQL = %s, want %s", sql, want)
+	}
+	if !reflect.DeepEqual(result.Args, []interface{}{int64(1), int64(2), "a%"}) {
+		t.Errorf("Args = %#v", result.Args)
+	}
+	if result.Page != 2 || result.Size != 100 {
+		t.Errorf("Page = %d, Size = %d", result.Page, result.Size)
+	}
+
+	values, _ = url.ParseQuery("filter[password]=x&filter[id]=abc&sort=userName")
+	_, err = parser.Parse(values)
+	if err == nil {
+		t.Fatal("Parse should fail")
+	}
+	for _, s := range []string{"password: is not filterable", "ID: must be an integer", "userName: is not sortable"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q should contain %q", err, s)
+		}
+	}
+	if err.(Error).Code() != 400 {
+		t.Errorf("error code = %d", err.(Error).Code())
+	}
+}
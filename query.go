@@ -3,13 +3,16 @@ package godac
 import (
 	"errors"
 	"godac/sqlbuilder"
+	"sync"
 )
 
 // Query represents a table created by sql query.
 type Query struct {
+	mu           sync.Mutex // Guards Open and Close.
 	active       bool
 	defaultTable *Table
 	keysMap      map[string]string
+	fieldsMap    map[string]Field
 
 	Selector sqlbuilder.Selector
 	Tables   []*Table
@@ -19,8 +22,10 @@ type Query struct {
 	OnDelete ActionFunc
 }
 
-// Open to init the query.
+// Open to init the query, it is safe to call Open concurrently.
 func (query *Query) Open() {
+	query.mu.Lock()
+	defer query.mu.Unlock()
 	if query.active {
 		return
 	}
@@ -29,6 +34,7 @@ func (query *Query) Open() {
 		query.defaultTable = query.Tables[0]
 	}
 	query.keysMap = map[string]string{}
+	query.fieldsMap = map[string]Field{}
 	fields := query.Fields
 	for _, table := range query.Tables {
 		fields = append(fields, table.Fields...)
@@ -36,20 +42,84 @@ func (query *Query) Open() {
 	for i := len(fields) - 1; i >= 0; i-- {
 		field := fields[i]
 		query.keysMap[field.Name] = field.GetKey()
+		query.fieldsMap[field.Name] = field
 	}
 	query.active = true
 }
 
-// Close the query.
+// Close the query, it must not be called concurrently with the other methods.
 func (query *Query) Close() {
+	query.mu.Lock()
+	defer query.mu.Unlock()
 	query.active = false
 }
 
-// Select query sql SELECT.
+// Select query sql SELECT. Include options in args load the related records of the default table, see Include.
 func (query *Query) Select(db DB, selector sqlbuilder.Selector, args ...interface{}) ([]Map, error) {
 	query.Open()
+	args, includes := splitIncludes(args)
 	qry := query.Selector.Merge(selector).SQL()
-	return MapQuery(query.keysMap, db, qry, args...)
+	rows, err := mapQuery(false, query.fieldsMap, db, qry, args...)
+	if err != nil || len(includes) == 0 {
+		return rows, err
+	}
+	if query.defaultTable == nil {
+		return nil, errors.New("Query.Tables undefined")
+	}
+	return rows, query.defaultTable.include(db, rows, includes)
+}
+
+// SelectTabular query sql SELECT, fetching rows to Tabular.
+func (query *Query) SelectTabular(db DB, selector sqlbuilder.Selector, args ...interface{}) (*Tabular, error) {
+	query.Open()
+	qry := query.Selector.Merge(selector).SQL()
+	return TabularQuery(query.fieldsMap, db, qry, args...)
+}
+
+// Count query SELECT COUNT(*), ORDER BY, LIMIT and OFFSET of selector are ignored.
+func (query *Query) Count(db DB, selector sqlbuilder.Selector, args ...interface{}) (int64, error) {
+	query.Open()
+	args, _ = splitIncludes(args)
+	return countQuery(db, query.Selector.Merge(selector).CountSQL(), args...)
+}
+
+// Page query a page of records, page starts from 1.
+func (query *Query) Page(db DB, selector sqlbuilder.Selector, page, size int64, args ...interface{}) (*Paged, error) {
+	return selectPage(query, db, selector, page, size, args...)
+}
+
+// Selector of the record by primary key of the default table, it is scoped by the WHERE of Query.Selector.
+func (query *Query) wherePrimaryKey(key Map) (sqlbuilder.Selector, []interface{}, error) {
+	query.Open()
+	if query.defaultTable == nil {
+		return query.Selector, nil, errors.New("Query.Tables undefined")
+	}
+	qry, args, err := query.defaultTable.WherePrimaryKey(true, false, key)
+	return query.Selector.WhereAndGroup(qry), args, err
+}
+
+// Get query the record by primary key of the default table in key, return ErrNotFound if it does not exist.
+func (query *Query) Get(db DB, key Map) (Map, error) {
+	selector, args, err := query.wherePrimaryKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return getRecord(query, db, selector, args...)
+}
+
+// ExistsKey check the record of primary key in key exists.
+func (query *Query) ExistsKey(db DB, key Map) (bool, error) {
+	selector, args, err := query.wherePrimaryKey(key)
+	if err != nil {
+		return false, err
+	}
+	count, err := query.Count(db, selector, args...)
+	return count > 0, err
+}
+
+// Reload requery the record by primary key, return a copy of record with the values in database.
+func (query *Query) Reload(db DB, record Map) (Map, error) {
+	return reloadRecord(query, db, record)
 }
 
 func (query *Query) execAction(c Context, onAction, defaultAction ActionFunc) (Result, error) {
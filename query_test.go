@@ -0,0 +1,28 @@
+package godac
+
+import (
+	"godac/sqlbuilder"
+	"reflect"
+	"testing"
+)
+
+func TestQueryScopedByKey(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	query := &Query{
+		Selector: sqlbuilder.Select().From("orders").Where("orders.deleted = 0 OR orders.archived = 0"),
+		Tables:   []*Table{testOrders},
+	}
+	if _, err := query.Get(db, Map{"id": int64(3)}); err != ErrNotFound {
+		t.Fatal(err)
+	}
+	if _, err := query.ExistsKey(db, Map{"id": int64(3)}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT * FROM orders WHERE (orders.deleted = 0 OR orders.archived = 0) AND (orders.id = ?) [3]",
+		"SELECT COUNT(*) FROM orders WHERE (orders.deleted = 0 OR orders.archived = 0) AND (orders.id = ?) [3]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
@@ -0,0 +1,196 @@
+package godac
+
+import (
+	"fmt"
+	"godac/sqlbuilder"
+	"strings"
+)
+
+// RelationKind is the kind of Relation.
+type RelationKind byte
+
+// RelationKinds enum.
+const (
+	RelationBelongsTo RelationKind = iota + 1
+	RelationHasMany
+	RelationManyToMany
+)
+
+// Relation defines the relationship from a table to the related Table.
+// The columns are matched by position, the omitted columns default to primary key:
+// BelongsTo: Columns reference References (default to primary key of the related table).
+// HasMany: References of the related table reference Columns (default to primary key).
+// ManyToMany: ThroughColumns of Through reference Columns (default to primary key),
+// ThroughReferences of Through reference References (default to primary key of the related table).
+type Relation struct {
+	Kind              RelationKind
+	Name              string       // Relation name
+	Key               string       // Map key of the loaded related records, default is Name
+	Table             *Table       // The related table
+	Columns           []string     // Columns of this table
+	References        []string     // Columns of the related table
+	Through           *Table       // The join table of ManyToMany
+	ThroughColumns    []string     // Columns of Through referencing Columns
+	ThroughReferences []string     // Columns of Through referencing References
+	OnDelete          DeleteAction // Action on the related records when a record is deleted, see DeleteRelations
+}
+
+// BelongsTo define a relation that columns of this table reference primary key of table.
+func BelongsTo(name string, table *Table, columns ...string) Relation {
+	return Relation{Kind: RelationBelongsTo, Name: name, Table: table, Columns: columns}
+}
+
+// HasMany define a relation that references of table reference primary key of this table.
+func HasMany(name string, table *Table, references ...string) Relation {
+	return Relation{Kind: RelationHasMany, Name: name, Table: table, References: references}
+}
+
+// ManyToMany define a relation through the join table, throughColumns of through reference primary key of
+// this table, and throughReferences of through reference primary key of table.
+func ManyToMany(name string, table, through *Table, throughColumns, throughReferences []string) Relation {
+	return Relation{Kind: RelationManyToMany, Name: name, Table: table, Through: through,
+		ThroughColumns: throughColumns, ThroughReferences: throughReferences}
+}
+
+// Get the column names of primary key.
+func (table *Table) primaryKeyColumns() []string {
+	var columns []string
+	for _, i := range table.primaryKey {
+		columns = append(columns, table.Fields[i].Name)
+	}
+	return columns
+}
+
+// Relation get the relation by name with the default columns resolved.
+func (table *Table) Relation(name string) (Relation, error) {
+	if err := table.Open(); err != nil {
+		return Relation{}, err
+	}
+	for _, rel := range table.Relations {
+		if rel.Name != name {
+			continue
+		}
+		if rel.Table == nil {
+			return rel, fmt.Errorf("Relation %s.%s: table undefined", table.Name, name)
+		}
+		if err := rel.Table.Open(); err != nil {
+			return rel, err
+		}
+		if len(rel.Columns) == 0 && rel.Kind != RelationBelongsTo {
+			rel.Columns = table.primaryKeyColumns()
+		}
+		if len(rel.References) == 0 && rel.Kind != RelationHasMany {
+			rel.References = rel.Table.primaryKeyColumns()
+		}
+		n := len(rel.Columns)
+		valid := n > 0 && len(rel.References) == n
+		if rel.Kind == RelationManyToMany {
+			valid = valid && rel.Through != nil && len(rel.ThroughColumns) == n && len(rel.ThroughReferences) == n
+		}
+		if !valid {
+			return rel, fmt.Errorf("Relation %s.%s: columns mismatch", table.Name, name)
+		}
+		return rel, nil
+	}
+	return Relation{}, fmt.Errorf("Relation %s.%s undefined", table.Name, name)
+}
+
+// Join conditions of left and right columns.
+func joinOn(left string, leftColumns []string, right string, rightColumns []string) string {
+	conditions := make([]string, len(leftColumns))
+	for i := range leftColumns {
+		conditions[i] = fmt.Sprintf("%s.%s = %s.%s", left, leftColumns[i], right, rightColumns[i])
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// Join add LEFT JOIN clauses of relation to selector, ManyToMany joins the Through table and the related table.
+func (table *Table) Join(selector sqlbuilder.Selector, name string) (sqlbuilder.Selector, error) {
+	rel, err := table.Relation(name)
+	if err != nil {
+		return selector, err
+	}
+	if rel.Kind == RelationManyToMany {
+		through := rel.Through.Name
+		selector = selector.LeftJoin(through, joinOn(through, rel.ThroughColumns, table.Name, rel.Columns))
+		return selector.LeftJoin(rel.Table.Name, joinOn(rel.Table.Name, rel.References, through, rel.ThroughReferences)), nil
+	}
+	return selector.LeftJoin(rel.Table.Name, joinOn(rel.Table.Name, rel.References, table.Name, rel.Columns)), nil
+}
+
+// Get the where condition and arguments of the related rows of record.
+// For ManyToMany, the condition is on the Through table.
+func (rel Relation) where(table *Table, record Map) (string, []interface{}, error) {
+	columns := rel.References
+	if rel.Kind == RelationManyToMany {
+		columns = rel.ThroughColumns
+	}
+	var conditions []string
+	var args []interface{}
+	for i, name := range rel.Columns {
+		value, exist := record[table.keysMap[name]]
+		if !exist {
+			return "", nil, fmt.Errorf("Relation %s: %s is required in record", rel.Name, table.keysMap[name])
+		}
+		conditions = append(conditions, columns[i]+" = "+Placeholder)
+		args = append(args, value)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// ExistsRelation return a checker used for checking the record has related records of HasMany or ManyToMany relation.
+func ExistsRelation(name string) CheckerFunc {
+	return func(c Context) error {
+		rel, err := c.Table.Relation(name)
+		if err != nil {
+			return err
+		}
+		count, err := rel.count(c.Table, c.DB, c.Record)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrExists
+		}
+		return nil
+	}
+}
+
+// Count the related rows of record, ManyToMany counts the rows of Through table.
+func (rel Relation) count(table *Table, db DB, record Map) (int64, error) {
+	query, args, err := rel.where(table, record)
+	if err != nil {
+		return 0, err
+	}
+	if rel.Kind == RelationManyToMany {
+		return rel.Through.Count(db, sqlbuilder.Select().Where(query), args...)
+	}
+	return rel.Table.Count(db, sqlbuilder.Select().Where(query), args...)
+}
+
+// InRelation return a validation rule that checks the field value exists in the related table of BelongsTo relation,
+// the field must be the first column of the relation.
+func InRelation(name string) ValidationRule {
+	return inRelationRule(name)
+}
+
+type inRelationRule string
+
+func (rule inRelationRule) Validate(value interface{}) error {
+	return ErrContextRequired
+}
+
+func (rule inRelationRule) ValidateContext(c Context, value interface{}) error {
+	rel, err := c.Table.Relation(string(rule))
+	if err != nil {
+		return err
+	}
+	if rel.Kind != RelationBelongsTo || rel.Columns[0] != c.Field.Name {
+		return fmt.Errorf("Relation %s: field %s is not the first column of BelongsTo", rel.Name, c.Field.Name)
+	}
+	in := In(rel.Table).On(rel.References[0])
+	for i := 1; i < len(rel.Columns); i++ {
+		in = in.With(rel.Columns[i], rel.References[i])
+	}
+	return in.ValidateContext(c, value)
+}
@@ -0,0 +1,157 @@
+package godac
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"godac/sqlbuilder"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	testCustomers = &Table{Name: "customers", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "name"}}}
+	testTags      = &Table{Name: "tags", Fields: []Field{{Name: "id", PrimaryKey: true}}}
+	testOrderTags = &Table{Name: "order_tags", Fields: []Field{{Name: "order_id", PrimaryKey: true}, {Name: "tag_id", PrimaryKey: true}}}
+	testLines     = &Table{Name: "order_lines", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "order_id"}}}
+	testOrders    = &Table{Name: "orders", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "customer_id"}},
+		Relations: []Relation{
+			BelongsTo("customer", testCustomers, "customer_id"),
+			HasMany("lines", testLines, "order_id"),
+			ManyToMany("tags", testTags, testOrderTags, []string{"order_id"}, []string{"tag_id"}),
+		}}
+)
+
+func TestRelationJoin(t *testing.T) {
+	selector := sqlbuilder.Select().From("orders")
+	var err error
+	for _, name := range []string{"customer", "lines", "tags"} {
+		if selector, err = testOrders.Join(selector, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "SELECT * FROM orders LEFT JOIN customers ON customers.id = orders.customer_id" +
+		" LEFT JOIN order_lines ON order_lines.order_id = orders.id" +
+		" LEFT JOIN order_tags ON order_tags.order_id = orders.id LEFT JOIN tags ON tags.id = order_tags.tag_id"
+	if got := selector.SQL(); got != want {
+		t.Errorf("SQL = %s\nwant %s", got, want)
+	}
+	if _, err := testOrders.Relation("payments"); err == nil {
+		t.Error("undefined relation should fail")
+	}
+
+	rel, _ := testOrders.Relation("lines")
+	query, args, err := rel.where(testOrders, Map{"id": int64(3)})
+	if err != nil || query != "order_id = ?" || args[0] != int64(3) {
+		t.Errorf("where = %s, %v, %v", query, args, err)
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	query, args := whereIn([]string{"a"}, [][]interface{}{{1}, {2}})
+	if query != "a IN (?, ?)" || len(args) != 2 {
+		t.Errorf("whereIn = %s, %v", query, args)
+	}
+	query, args = whereIn([]string{"a", "b"}, [][]interface{}{{1, 2}, {3, 4}})
+	if query != "((a = ? AND b = ?) OR (a = ? AND b = ?))" || len(args) != 4 {
+		t.Errorf("whereIn = %s, %v", query, args)
+	}
+	args, paths := splitIncludes([]interface{}{1, Include("lines.product"), "x", Include("customer")})
+	if len(args) != 2 || len(paths) != 2 || paths[1] != "customer" {
+		t.Errorf("splitIncludes = %v, %v", args, paths)
+	}
+}
+
+func TestDeleterError(t *testing.T) {
+	restrict := func(Context) error { return NewError(fmt.Sprintf(ErrorFormatOnRestrict, "Order Lines"), 400) }
+	_, err := Deleter(restrict)(Context{Table: testOrders})
+	if err == nil || err.Error() != "can not be deleted, it is in use by Order Lines" {
+		t.Fatalf("err = %v", err)
+	}
+	cascade := func(Context) error { return err }
+	if _, err2 := Deleter(cascade)(Context{Table: testOrders}); err2 != err {
+		t.Errorf("cascaded err = %v", err2)
+	}
+}
+
+func TestLoadThrough(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	rel, err := testOrders.Relation("tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rel.loadThrough(db, [][]interface{}{{int64(1)}, {int64(2)}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SELECT tags.id, order_tags.order_id AS godac_through_0 FROM order_tags " +
+		"INNER JOIN tags ON tags.id = order_tags.tag_id WHERE order_tags.order_id IN (?, ?) [1 2]"}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func withDeleteAction(rel Relation, action DeleteAction) Relation {
+	rel.OnDelete = action
+	return rel
+}
+
+func TestDeleteRelations(t *testing.T) {
+	books := &Table{Name: "books", Title: "Books", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "author_id"}}}
+	notes := &Table{Name: "notes", Title: "Notes", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "author_id"}}}
+	posts := &Table{Name: "posts", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "author_id"}}}
+	drafts := &Table{Name: "drafts", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "author_id"}, {Name: "editor_id"}}}
+	tags := &Table{Name: "tags", Fields: []Field{{Name: "id", PrimaryKey: true}}}
+	authorTags := &Table{Name: "author_tags", Fields: []Field{{Name: "author_id", PrimaryKey: true}, {Name: "tag_id", PrimaryKey: true}}}
+	authors := &Table{Name: "authors", Fields: []Field{{Name: "id", PrimaryKey: true}},
+		Relations: []Relation{
+			withDeleteAction(HasMany("books", books, "author_id"), DeleteRestrict),
+			withDeleteAction(HasMany("posts", posts, "author_id"), DeleteCascade),
+			withDeleteAction(HasMany("drafts", drafts, "author_id"), DeleteSetNull),
+			withDeleteAction(ManyToMany("tags", tags, authorTags, []string{"author_id"}, []string{"tag_id"}), DeleteCascade),
+			withDeleteAction(HasMany("notes", notes, "author_id"), DeleteRestrict),
+			HasMany("comments", notes, "author_id"),
+		}}
+	c := Context{StateDelete, nil, authors, authors, Map{"id": int64(1)}, Field{}}
+
+	var inUse bool
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		switch {
+		case strings.Contains(query, "COUNT(*)") && inUse:
+			return []string{"count"}, [][]driver.Value{{int64(1)}}
+		case strings.HasPrefix(query, "SELECT id, author_id FROM posts"):
+			return []string{"id", "author_id"}, [][]driver.Value{{int64(7), int64(1)}, {int64(8), int64(1)}}
+		}
+		return nil, nil
+	})
+	c.DB = db
+
+	inUse = true
+	err := DeleteRelations(c)
+	if err == nil || err.Error() != "it is in use by Books, Notes" {
+		t.Errorf("restrict error = %v", err)
+	}
+	want := []string{
+		"SELECT COUNT(*) FROM books WHERE author_id = ? [1]",
+		"SELECT COUNT(*) FROM notes WHERE author_id = ? [1]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("restrict statements = %q", got)
+	}
+
+	inUse = false
+	if err := DeleteRelations(c); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want,
+		"SELECT COUNT(*) FROM books WHERE author_id = ? [1]",
+		"SELECT COUNT(*) FROM notes WHERE author_id = ? [1]",
+		"SELECT id, author_id FROM posts WHERE author_id = ? [1]",
+		"DELETE FROM posts WHERE id = ? [7]",
+		"DELETE FROM posts WHERE id = ? [8]",
+		"UPDATE drafts SET author_id = NULL WHERE author_id = ? [1]",
+		"DELETE FROM author_tags WHERE author_id = ? [1]",
+	)
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
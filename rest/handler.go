@@ -0,0 +1,298 @@
+// Package rest serves godac DataSet as REST resource by net/http.
+package rest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"godac"
+	"godac/sqlbuilder"
+	"net/http"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Handler serves a DataSet (*godac.Table, *godac.Query or *godac.MasterDetail) as CRUD resource:
+//
+//	GET    /          list records with filter, sort and page parameters, see godac.QueryParser
+//	POST   /          insert a record
+//	GET    /{key}     get a record by primary key
+//	PUT    /{key}     replace a record, the writable fields absent in request body are set to NULL
+//	PATCH  /{key}     update the fields of a record in request body
+//	DELETE /{key}     delete a record
+//
+// The values of composite primary key are separate path segments in order of primary key fields.
+// The list of *godac.Query is scoped by the WHERE of Query.Selector, the filter conditions are combined with it by AND.
+// Use http.StripPrefix to mount it on a sub path. Each request is executed in a transaction.
+type Handler struct {
+	DataSet     godac.DataSet
+	DB          *sql.DB
+	Parser      godac.QueryParser // Parser of list parameters, Parser.Fields defaults to the fields of DataSet.
+	MaxBodySize int64             // Max request body size, default is 1MB.
+}
+
+// NewHandler create a Handler of DataSet.
+func NewHandler(db *sql.DB, ds godac.DataSet) *Handler {
+	return &Handler{DataSet: ds, DB: db}
+}
+
+// Errors of request.
+var (
+	ErrMethodNotAllowed = godac.NewError("method not allowed", http.StatusMethodNotAllowed)
+	ErrBadKey           = godac.NewError("invalid primary key in path", http.StatusBadRequest)
+	ErrBadBody          = godac.NewError("invalid JSON object in request body", http.StatusBadRequest)
+)
+
+// Get all fields and primary key fields of DataSet.
+func (h *Handler) fields() (fields, primaryKey []godac.Field) {
+	switch ds := h.DataSet.(type) {
+	case *godac.Table:
+		fields = ds.Fields
+	case *godac.MasterDetail:
+		fields = ds.Master.Fields
+	case *godac.Query:
+		fields = append(fields, ds.Fields...)
+		for _, table := range ds.Tables {
+			fields = append(fields, table.Fields...)
+		}
+		if len(ds.Tables) > 0 {
+			for _, field := range ds.Tables[0].Fields {
+				if field.PrimaryKey {
+					primaryKey = append(primaryKey, field)
+				}
+			}
+		}
+		return
+	}
+	for _, field := range fields {
+		if field.PrimaryKey {
+			primaryKey = append(primaryKey, field)
+		}
+	}
+	return
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	var segments []string
+	if path != "" {
+		segments = strings.Split(path, "/")
+	}
+
+	var action func(db godac.DB) (int, interface{}, error)
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		action = func(db godac.DB) (int, interface{}, error) { return h.list(db, r) }
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		action = func(db godac.DB) (int, interface{}, error) { return h.insert(db, w, r) }
+	case len(segments) > 0:
+		key, err := h.key(segments)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			action = func(db godac.DB) (int, interface{}, error) { return h.get(db, key) }
+		case http.MethodPut, http.MethodPatch:
+			action = func(db godac.DB) (int, interface{}, error) { return h.update(db, w, r, key) }
+		case http.MethodDelete:
+			action = func(db godac.DB) (int, interface{}, error) { return h.delete(db, key) }
+		}
+	}
+	if action == nil {
+		writeError(w, ErrMethodNotAllowed)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	status, body, err := action(godac.WithContext(r.Context(), tx))
+	if err != nil {
+		tx.Rollback()
+		writeError(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, body)
+}
+
+// Get primary key record from path segments.
+func (h *Handler) key(segments []string) (godac.Map, error) {
+	_, primaryKey := h.fields()
+	if len(segments) != len(primaryKey) {
+		return nil, ErrBadKey
+	}
+	key := godac.Map{}
+	for i, field := range primaryKey {
+		value, err := field.Coerce(segments[i])
+		if err != nil {
+			return nil, ErrBadKey
+		}
+		key[field.GetKey()] = value
+	}
+	return key, nil
+}
+
+// Decode JSON object of request body.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request) (godac.Map, error) {
+	size := h.MaxBodySize
+	if size <= 0 {
+		size = 1 << 20
+	}
+	var record godac.Map
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, size)).Decode(&record); err != nil || record == nil {
+		return nil, ErrBadBody
+	}
+	return record, nil
+}
+
+func (h *Handler) list(db godac.DB, r *http.Request) (int, interface{}, error) {
+	parser := h.Parser
+	if parser.Fields == nil {
+		parser.Fields, _ = h.fields()
+	}
+	query, err := parser.Parse(r.URL.Query())
+	if err != nil {
+		return 0, nil, err
+	}
+	page, err := h.DataSet.Page(db, h.scope(query.Selector), query.Page, query.Size, query.Args...)
+	return http.StatusOK, page, err
+}
+
+// Combine selector with the Selector of *godac.Query, so the WHERE of Query is not replaced.
+func (h *Handler) scope(selector sqlbuilder.Selector) sqlbuilder.Selector {
+	if query, ok := h.DataSet.(*godac.Query); ok {
+		return query.Selector.MergeAnd(selector)
+	}
+	return selector
+}
+
+// Set the writable fields absent in record to godac.Null, it makes PUT replace the whole record.
+// The fields of primary key, auto-increment, read-only and OnUpdate are excluded.
+func (h *Handler) replace(record godac.Map) {
+	var fields []godac.Field
+	switch ds := h.DataSet.(type) {
+	case *godac.Table:
+		fields = ds.Fields
+	case *godac.MasterDetail:
+		fields = ds.Master.Fields
+	case *godac.Query:
+		if len(ds.Tables) > 0 {
+			fields = ds.Tables[0].Fields
+		}
+	}
+	for _, field := range fields {
+		if field.PrimaryKey || field.AutoInc || field.ReadOnly || field.OnUpdate != nil {
+			continue
+		}
+		if _, exist := record[field.GetKey()]; !exist {
+			record[field.GetKey()] = godac.Null
+		}
+	}
+}
+
+func (h *Handler) get(db godac.DB, key godac.Map) (int, interface{}, error) {
+	record, err := h.DataSet.Get(db, key)
+	return http.StatusOK, record, err
+}
+
+func (h *Handler) insert(db godac.DB, w http.ResponseWriter, r *http.Request) (int, interface{}, error) {
+	record, err := h.decode(w, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	result, err := h.DataSet.Insert(db, record)
+	if err != nil {
+		return 0, nil, err
+	}
+	record, err = result.Record(true)
+	return http.StatusCreated, record, err
+}
+
+func (h *Handler) update(db godac.DB, w http.ResponseWriter, r *http.Request, key godac.Map) (int, interface{}, error) {
+	record, err := h.decode(w, r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if r.Method == http.MethodPut {
+		h.replace(record)
+	}
+	if err := h.exists(db, key); err != nil {
+		return 0, nil, err
+	}
+	for k, v := range key {
+		record[k] = v
+	}
+	result, err := h.DataSet.Update(db, record)
+	if err != nil {
+		return 0, nil, err
+	}
+	record, err = result.Record(true)
+	return http.StatusOK, record, err
+}
+
+func (h *Handler) delete(db godac.DB, key godac.Map) (int, interface{}, error) {
+	if err := h.exists(db, key); err != nil {
+		return 0, nil, err
+	}
+	_, err := h.DataSet.Delete(db, key)
+	return http.StatusNoContent, nil, err
+}
+
+// Return godac.ErrNotFound if the record of key does not exist.
+func (h *Handler) exists(db godac.DB, key godac.Map) error {
+	exists, err := h.DataSet.ExistsKey(db, key)
+	if err == nil && !exists {
+		return godac.ErrNotFound
+	}
+	return err
+}
+
+// ErrorStatus get the HTTP status code of error:
+// the code of godac.Error, 422 for validation errors and 500 for the others.
+func ErrorStatus(err error) int {
+	var e godac.Error
+	if errors.As(err, &e) && e.Code() >= 400 && e.Code() < 600 {
+		return e.Code()
+	}
+	switch err.(type) {
+	case validation.Error, validation.Errors:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// Error response body.
+type errorBody struct {
+	Error  string                `json:"error"`
+	Errors godac.ValidationError `json:"errors,omitempty"` // Errors of fields
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := ErrorStatus(err)
+	body := errorBody{Error: err.Error()}
+	if status == http.StatusInternalServerError {
+		body.Error = http.StatusText(status)
+	}
+	errors.As(err, &body.Errors)
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"errors"
+	"godac"
+	"godac/sqlbuilder"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+var orders = &godac.Table{Name: "order_lines", Fields: []godac.Field{
+	{Name: "order_id", PrimaryKey: true, Type: godac.TypeInt},
+	{Name: "line_no", PrimaryKey: true, Type: godac.TypeInt},
+	{Name: "qty"},
+}}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{godac.ErrNotFound, http.StatusNotFound},
+		{godac.ErrExists, http.StatusBadRequest},
+		{validation.ErrRequired, http.StatusUnprocessableEntity},
+		{errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if got := ErrorStatus(test.err); got != test.want {
+			t.Errorf("ErrorStatus(%v) = %d, want %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestHandlerKey(t *testing.T) {
+	h := NewHandler(nil, orders)
+	key, err := h.key([]string{"7", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key["orderID"] != int64(7) || key["lineNo"] != int64(2) {
+		t.Errorf("key = %v", key)
+	}
+	if _, err := h.key([]string{"7"}); err != ErrBadKey {
+		t.Errorf("key error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/7/2", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /7/2 status = %d", w.Code)
+	}
+}
+
+func TestHandlerScope(t *testing.T) {
+	query := &godac.Query{
+		Selector: sqlbuilder.Select().From("order_lines").Where("order_lines.deleted = 0 OR order_lines.archived = 0"),
+		Tables:   []*godac.Table{orders},
+	}
+	h := NewHandler(nil, query)
+	h.Parser.Fields, _ = h.fields()
+	list, err := h.Parser.Parse(url.Values{"filter[qty][gt]": {"1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := h.scope(list.Selector).SQL()
+	if want := "SELECT * FROM order_lines WHERE (order_lines.deleted = 0 OR order_lines.archived = 0) AND (qty > ?)"; s != want {
+		t.Errorf("SQL = %q, want %q", s, want)
+	}
+}
+
+func TestHandlerReplace(t *testing.T) {
+	h := NewHandler(nil, orders)
+	record := godac.Map{"orderID": int64(7)}
+	h.replace(record)
+	if record["qty"] != godac.Null || len(record) != 2 {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestHandlerMasterDetail(t *testing.T) {
+	lines := &godac.Table{Name: "invoice_lines", Fields: []godac.Field{{Name: "id", PrimaryKey: true}, {Name: "invoice_id"}}}
+	invoices := &godac.Table{Name: "invoices", Fields: []godac.Field{
+		{Name: "id", PrimaryKey: true, Type: godac.TypeInt}, {Name: "customer_id"},
+	}, Relations: []godac.Relation{godac.HasMany("lines", lines, "invoice_id")}}
+	h := NewHandler(nil, &godac.MasterDetail{Master: invoices, Details: []string{"lines"}})
+	key, err := h.key([]string{"3"})
+	if err != nil || key["id"] != int64(3) {
+		t.Fatalf("key = %v, err = %v", key, err)
+	}
+	record := godac.Map{"id": int64(3), "lines": []interface{}{}}
+	h.replace(record)
+	if record["customerID"] != godac.Null || len(record) != 3 {
+		t.Errorf("record = %v", record)
+	}
+}
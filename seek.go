@@ -0,0 +1,204 @@
+package godac
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"godac/sqlbuilder"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned by Seek when the cursor cannot be decoded.
+var ErrInvalidCursor = NewError("invalid cursor", 400)
+
+func init() {
+	gob.Register(time.Time{})
+	gob.Register(Decimal(""))
+}
+
+// SortKey is a sort column of keyset pagination, the column values must not be NULL.
+type SortKey struct {
+	Column string // Column name, it may be qualified by table name.
+	Desc   bool
+}
+
+// Keyset is a page of keyset pagination.
+type Keyset struct {
+	Rows []Map  `json:"rows"`
+	Next string `json:"next"` // The cursor of next page, empty if it is the last page.
+	Prev string `json:"prev"` // The cursor of previous page, empty if it is the first page.
+}
+
+// Keyset cursor content.
+type cursor struct {
+	Values []interface{}
+	Back   bool // Seek backward for previous page.
+}
+
+func (c cursor) encode() (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(c); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeCursor(s string, n int) (c cursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&c)
+	}
+	if err != nil || len(c.Values) != n {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Seek query a page of records after or before cursor by keyset pagination, an empty cursor means the first page.
+// order defaults to primary key, and the primary key columns are appended to order if missing to make it unique.
+func (table *Table) Seek(db DB, selector sqlbuilder.Selector, order []SortKey, cursor string, size int64, args ...interface{}) (*Keyset, error) {
+	if err := table.Open(); err != nil {
+		return nil, err
+	}
+	order = appendPrimaryKey(table, false, order)
+	return seek(table, table.keysMap, db, selector, order, cursor, size, args...)
+}
+
+// Seek query a page of records after or before cursor by keyset pagination, see Table.Seek.
+// order defaults to primary key of the default table.
+func (query *Query) Seek(db DB, selector sqlbuilder.Selector, order []SortKey, cursor string, size int64, args ...interface{}) (*Keyset, error) {
+	query.Open()
+	if query.defaultTable == nil {
+		return nil, fmt.Errorf("Query.Tables undefined")
+	}
+	if err := query.defaultTable.Open(); err != nil {
+		return nil, err
+	}
+	order = appendPrimaryKey(query.defaultTable, true, order)
+	return seek(query, query.keysMap, db, query.Selector.MergeAnd(selector), order, cursor, size, args...)
+}
+
+// Append the primary key columns of table to order if missing.
+func appendPrimaryKey(table *Table, hasTableName bool, order []SortKey) []SortKey {
+	result := append([]SortKey{}, order...)
+	for _, i := range table.primaryKey {
+		name := table.Fields[i].Name
+		found := false
+		for _, key := range order {
+			if key.Column == name || key.Column == table.Name+"."+name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if hasTableName {
+				name = table.Name + "." + name
+			}
+			result = append(result, SortKey{Column: name})
+		}
+	}
+	return result
+}
+
+func seek(ds DataSet, keysMap map[string]string, db DB, selector sqlbuilder.Selector, order []SortKey, cursorText string, size int64, args ...interface{}) (*Keyset, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("Invalid page size %d", size)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("Keyset order undefined")
+	}
+	keys := make([]string, len(order))
+	for i, key := range order {
+		name := key.Column[strings.LastIndex(key.Column, ".")+1:]
+		if keys[i] = keysMap[name]; keys[i] == "" {
+			keys[i] = convertName(name)
+		}
+	}
+	var cur cursor
+	if cursorText != "" {
+		var err error
+		if cur, err = decodeCursor(cursorText, len(order)); err != nil {
+			return nil, err
+		}
+		condition, condArgs := keysetCondition(order, cur)
+		selector = selector.WhereAndGroup(condition)
+		args = append(args, condArgs...)
+	}
+	var orderBy []string
+	for _, key := range order {
+		if key.Desc != cur.Back {
+			orderBy = append(orderBy, key.Column+" DESC")
+		} else {
+			orderBy = append(orderBy, key.Column)
+		}
+	}
+	selector = selector.OrderBy(strings.Join(orderBy, sqlbuilder.ColSep)).Limit(size + 1)
+	rows, err := ds.Select(db, selector, args...)
+	if err != nil {
+		return nil, err
+	}
+	more := int64(len(rows)) > size
+	if more {
+		rows = rows[:size]
+	}
+	if cur.Back {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	result := &Keyset{Rows: rows}
+	if result.Rows == nil {
+		result.Rows = []Map{}
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+	if more || cur.Back {
+		if result.Next, err = rowCursor(rows[len(rows)-1], keys, false); err != nil {
+			return nil, err
+		}
+	}
+	if cursorText != "" && (more || !cur.Back) {
+		if result.Prev, err = rowCursor(rows[0], keys, true); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Get the cursor of row.
+func rowCursor(row Map, keys []string, back bool) (string, error) {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := row[key]
+		if !ok {
+			return "", fmt.Errorf("Keyset column %s is not in record or it is NULL", key)
+		}
+		values[i] = value
+	}
+	return cursor{values, back}.encode()
+}
+
+// Get the where condition of rows after cursor, e.g. for order a, b DESC:
+// (a > ? OR a = ? AND b < ?)
+func keysetCondition(order []SortKey, cur cursor) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for i, key := range order {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, order[j].Column+" = ?")
+			args = append(args, cur.Values[j])
+		}
+		operator := ">"
+		if key.Desc != cur.Back {
+			operator = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s ?", key.Column, operator))
+		args = append(args, cur.Values[i])
+		conditions = append(conditions, strings.Join(terms, " AND "))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
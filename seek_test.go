@@ -0,0 +1,62 @@
+package godac
+
+import (
+	"godac/sqlbuilder"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysetCondition(t *testing.T) {
+	order := []SortKey{{Column: "created", Desc: true}, {Column: "id"}}
+	cond, args := keysetCondition(order, cursor{Values: []interface{}{"c", int64(1)}})
+	if want := "(created < ? OR created = ? AND id > ?)"; cond != want {
+		t.Errorf("condition = %s, want %s", cond, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"c", "c", int64(1)}) {
+		t.Errorf("args = %v", args)
+	}
+	cond, _ = keysetCondition(order, cursor{Values: []interface{}{"c", int64(1)}, Back: true})
+	if want := "(created > ? OR created = ? AND id < ?)"; cond != want {
+		t.Errorf("backward condition = %s, want %s", cond, want)
+	}
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	text, err := rowCursor(Map{"created": now, "id": int64(1)}, []string{"created", "id"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := decodeCursor(text, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Back || !c.Values[0].(time.Time).Equal(now) || c.Values[1] != int64(1) {
+		t.Errorf("cursor = %#v", c)
+	}
+	if _, err := decodeCursor(text, 3); err != ErrInvalidCursor {
+		t.Errorf("decodeCursor error = %v", err)
+	}
+}
+
+func TestSeekScope(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	order := []SortKey{{Column: "id"}}
+	text, err := rowCursor(Map{"id": int64(5)}, []string{"id"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testOrders.Seek(db, sqlbuilder.Select().Where("customer_id = ? OR customer_id = ?"), order, text, 10, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	query := &Query{Selector: sqlbuilder.Select().From("orders").Where("orders.deleted = 0 OR orders.archived = 0"), Tables: []*Table{testOrders}}
+	if _, err := query.Seek(db, sqlbuilder.Select().Where("orders.customer_id = ?"), nil, "", 10, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT id, customer_id FROM orders WHERE (customer_id = ? OR customer_id = ?) AND ((id > ?)) ORDER BY id LIMIT 11 [1 2 5]",
+		"SELECT * FROM orders WHERE (orders.deleted = 0 OR orders.archived = 0) AND (orders.customer_id = ?) ORDER BY orders.id LIMIT 11 [1]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
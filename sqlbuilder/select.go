@@ -15,9 +15,9 @@ func Select() Selector {
 
 // Selector is a sql builder for SELECT.
 type Selector struct {
-	columns, joins       []string
-	from, where, orderBy string
-	limit, offset        *int64
+	columns, joins                        []string
+	from, where, groupBy, having, orderBy string
+	limit, offset                         *int64
 }
 
 // Merge source to target
@@ -34,6 +34,12 @@ func (sql Selector) Merge(src Selector) Selector {
 	if src.where != "" {
 		sql.where = src.where
 	}
+	if src.groupBy != "" {
+		sql.groupBy = src.groupBy
+	}
+	if src.having != "" {
+		sql.having = src.having
+	}
 	if src.orderBy != "" {
 		sql.orderBy = src.orderBy
 	}
@@ -46,6 +52,18 @@ func (sql Selector) Merge(src Selector) Selector {
 	return sql
 }
 
+// MergeAnd merge source to target like Merge, but the WHERE clauses are combined by WhereAndGroup,
+// so the target WHERE stays in effect as a scope.
+func (sql Selector) MergeAnd(src Selector) Selector {
+	where := sql.where
+	sql = sql.Merge(src)
+	if where != "" && src.where != "" {
+		sql.where = where
+		sql = sql.WhereAndGroup(src.where)
+	}
+	return sql
+}
+
 // Columns set columns.
 func (sql Selector) Columns(columns ...string) Selector {
 	sql.columns = columns
@@ -64,6 +82,12 @@ func (sql Selector) LeftJoin(joined, on string) Selector {
 	return sql
 }
 
+// InnerJoin set INNER JOIN clause.
+func (sql Selector) InnerJoin(joined, on string) Selector {
+	sql.joins = append(sql.joins, fmt.Sprintf("INNER JOIN %s ON %s", joined, on))
+	return sql
+}
+
 // Where set WHERE clause.
 func (sql Selector) Where(where string) Selector {
 	sql.where = where
@@ -79,6 +103,27 @@ func (sql Selector) WhereAnd(where string) Selector {
 	return sql
 }
 
+// WhereAndGroup set WHERE clause to (WHERE) AND (where), the conditions on both sides keep their OR precedence.
+func (sql Selector) WhereAndGroup(where string) Selector {
+	if sql.where == "" {
+		return sql.Where(where)
+	}
+	sql.where = "(" + sql.where + ") AND (" + where + ")"
+	return sql
+}
+
+// GroupBy set GROUP BY clause.
+func (sql Selector) GroupBy(groupBy string) Selector {
+	sql.groupBy = groupBy
+	return sql
+}
+
+// Having set HAVING clause.
+func (sql Selector) Having(having string) Selector {
+	sql.having = having
+	return sql
+}
+
 // OrderBy set ORDER BY clause.
 func (sql Selector) OrderBy(orderBy string) Selector {
 	sql.orderBy = orderBy
@@ -113,6 +158,8 @@ func (sql Selector) SQL() string {
 		s += " " + strings.Join(sql.joins, " ")
 	}
 	s = iifAdd(s, sql.where != "", " WHERE "+sql.where, "")
+	s = iifAdd(s, sql.groupBy != "", " GROUP BY "+sql.groupBy, "")
+	s = iifAdd(s, sql.having != "", " HAVING "+sql.having, "")
 	s = iifAdd(s, sql.orderBy != "", " ORDER BY "+sql.orderBy, "")
 	if sql.limit != nil {
 		s += fmt.Sprintf(" LIMIT %d", *sql.limit)
@@ -122,3 +169,15 @@ func (sql Selector) SQL() string {
 	}
 	return s
 }
+
+// CountSQL get SELECT COUNT(*) sql, ORDER BY, LIMIT and OFFSET are ignored.
+// The query is wrapped as a subquery if it has GROUP BY clause.
+func (sql Selector) CountSQL() string {
+	sql.orderBy = ""
+	sql.limit = nil
+	sql.offset = nil
+	if sql.groupBy == "" {
+		return sql.Columns("COUNT(*)").SQL()
+	}
+	return "SELECT COUNT(*) FROM (" + sql.Columns("1").SQL() + ") count_query"
+}
@@ -0,0 +1,39 @@
+package sqlbuilder
+
+import "testing"
+
+func TestCountSQL(t *testing.T) {
+	tests := []struct {
+		selector Selector
+		want     string
+	}{
+		{Select().From("a").Where("x = ?").OrderBy("id").Limit(10).Offset(20), "SELECT COUNT(*) FROM a WHERE x = ?"},
+		{Select().Columns("a.k", "SUM(b.v)").From("a").LeftJoin("b", "b.k = a.k").GroupBy("a.k").Having("SUM(b.v) > 0"),
+			"SELECT COUNT(*) FROM (SELECT 1 FROM a LEFT JOIN b ON b.k = a.k GROUP BY a.k HAVING SUM(b.v) > 0) count_query"},
+	}
+	for _, test := range tests {
+		if got := test.selector.CountSQL(); got != test.want {
+			t.Errorf("CountSQL = %s, want %s", got, test.want)
+		}
+	}
+}
+
+func TestWhereAndGroup(t *testing.T) {
+	s := Select().From("t").Where("a = 1 OR b = 2").WhereAndGroup("c = 3 OR d = 4").SQL()
+	if s != "SELECT * FROM t WHERE (a = 1 OR b = 2) AND (c = 3 OR d = 4)" {
+		t.Errorf("SQL = %q", s)
+	}
+	if s := Select().From("t").WhereAndGroup("c = 3").SQL(); s != "SELECT * FROM t WHERE c = 3" {
+		t.Errorf("SQL = %q", s)
+	}
+}
+
+func TestMergeAnd(t *testing.T) {
+	base := Select().From("t").Where("a = 1 OR b = 2").OrderBy("a")
+	if s := base.MergeAnd(Select().Where("c = 3").OrderBy("c")).SQL(); s != "SELECT * FROM t WHERE (a = 1 OR b = 2) AND (c = 3) ORDER BY c" {
+		t.Errorf("SQL = %q", s)
+	}
+	if s := base.MergeAnd(Select().Limit(1)).SQL(); s != "SELECT * FROM t WHERE a = 1 OR b = 2 ORDER BY a LIMIT 1" {
+		t.Errorf("SQL = %q", s)
+	}
+}
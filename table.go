@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"godac/sqlbuilder"
+	"reflect"
 	"strings"
+	"sync"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
@@ -14,22 +16,29 @@ const Placeholder = "?"
 
 // Table is a sql database table.
 type Table struct {
+	mu         sync.Mutex // Guards Open and Close.
 	active     bool
 	cols       []string
 	keys       []string
 	keysMap    map[string]string
+	fieldsMap  map[string]Field
 	primaryKey []int // Indexes of primary key fields.
 	autoInc    int   // index of AutoInc field.
 
-	Name     string
-	Fields   []Field
-	OnInsert ActionFunc
-	OnUpdate ActionFunc
-	OnDelete ActionFunc
+	Name       string
+	Title      string // The caption of table, used for display errors, etc...
+	Fields     []Field
+	Validators []RecordValidator // Record validators, they run after field validations, the keys which already have an error are skipped.
+	Relations  []Relation        // Relationships to the other tables.
+	OnInsert   ActionFunc
+	OnUpdate   ActionFunc
+	OnDelete   ActionFunc
 }
 
-// Open init the Table.
+// Open init the Table, it is safe to call Open concurrently.
 func (table *Table) Open() error {
+	table.mu.Lock()
+	defer table.mu.Unlock()
 	if table.active {
 		return nil
 	}
@@ -39,6 +48,7 @@ func (table *Table) Open() error {
 	table.cols = []string{}
 	table.keys = []string{}
 	table.keysMap = map[string]string{}
+	table.fieldsMap = map[string]Field{}
 	table.primaryKey = []int{}
 	table.autoInc = -1
 	for i, field := range table.Fields {
@@ -49,6 +59,7 @@ func (table *Table) Open() error {
 		key := field.GetKey()
 		table.keys = append(table.keys, key)
 		table.keysMap[field.Name] = key
+		table.fieldsMap[field.Name] = field
 		if field.PrimaryKey {
 			table.primaryKey = append(table.primaryKey, i)
 		}
@@ -60,18 +71,42 @@ func (table *Table) Open() error {
 	return nil
 }
 
-// Close the table.
+// GetTitle get real title.
+func (table *Table) GetTitle() string {
+	if table.Title == "" {
+		return Field{Name: table.Name}.GetTitle()
+	}
+	return table.Title
+}
+
+// Close the table, it must not be called concurrently with the other methods.
 func (table *Table) Close() {
+	table.mu.Lock()
+	defer table.mu.Unlock()
 	table.active = false
 }
 
-// Select query sql SELECT.
+// Select query sql SELECT. Include options in args load the related records, see Include.
 func (table *Table) Select(db DB, selector sqlbuilder.Selector, args ...interface{}) ([]Map, error) {
+	if err := table.Open(); err != nil {
+		return nil, err
+	}
+	args, includes := splitIncludes(args)
+	query := selector.Columns(table.cols...).From(table.Name).SQL()
+	rows, err := mapQuery(false, table.fieldsMap, db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, table.include(db, rows, includes)
+}
+
+// SelectTabular query sql SELECT, fetching rows to Tabular.
+func (table *Table) SelectTabular(db DB, selector sqlbuilder.Selector, args ...interface{}) (*Tabular, error) {
 	if err := table.Open(); err != nil {
 		return nil, err
 	}
 	query := selector.Columns(table.cols...).From(table.Name).SQL()
-	return MapQuery(table.keysMap, db, query, args...)
+	return TabularQuery(table.fieldsMap, db, query, args...)
 }
 
 func (table *Table) execAction(c Context, onAction, defaultAction ActionFunc) (Result, error) {
@@ -92,42 +127,78 @@ func (table *Table) Insert(db DB, record Map) (Result, error) {
 
 // DefaultInsert is default Insert handler.
 func (table *Table) DefaultInsert(c Context) (Result, error) {
-	if err := table.Open(); err != nil {
+	rec, query, args, err := table.insertStatement(c)
+	if err != nil {
 		return nil, err
 	}
+	return table.exec(Context{StateInsert, c.DB, c.DataSet, table, rec, Field{}}, query, args...)
+}
+
+// Resolve and validate the values of c.Record, return the final record and the INSERT statement.
+func (table *Table) insertStatement(c Context) (Map, string, []interface{}, error) {
+	if err := table.Open(); err != nil {
+		return nil, "", nil, err
+	}
 	var rec = Map{}
 	for k, v := range c.Record {
 		rec[k] = v
 	}
-	var cols []string
-	var placeholders []string
-	var args []interface{}
+	values := make([]interface{}, len(table.Fields))
+	included := make([]bool, len(table.Fields))
+	errs := ValidationError{}
 	for i, field := range table.Fields {
 		if field.AutoInc {
 			continue
 		}
-		value := c.Record[table.keys[i]]
+		value, err := field.Coerce(c.Record[table.keys[i]])
+		if err != nil {
+			if err = errs.Add(table.keys[i], field, err); err != nil {
+				return nil, "", nil, err
+			}
+			continue
+		}
 		if field.ReadOnly {
-			if field.Default == nil {
+			if field.Default == nil && field.Generator == nil {
 				continue
 			}
 			value = nil
 		}
+		fc := Context{StateInsert, c.DB, c.DataSet, table, rec, field}
+		if value == nil && field.Generator != nil {
+			if value, err = field.Generator.Generate(); err != nil {
+				return nil, "", nil, err
+			}
+		}
 		if value == nil {
-			value = field.GetDefault()
+			value = evalValue(field.Default, fc)
 		}
 		rec[table.keys[i]] = value
-		if err := validateField(Context{StateInsert, c.DB, c.DataSet, table, rec, field}, value); err != nil {
-			return nil, err
+		values[i], included[i] = value, true
+	}
+	if err := table.validateValues(Context{StateInsert, c.DB, c.DataSet, table, rec, Field{}}, values, included, errs); err != nil {
+		return nil, "", nil, err
+	}
+	var cols []string
+	var placeholders []string
+	var args []interface{}
+	for i, field := range table.Fields {
+		if !included[i] {
+			continue
 		}
+		placeholder, valueArgs := bindValue(values[i])
 		cols = append(cols, field.Name)
-		placeholders = append(placeholders, Placeholder)
-		args = append(args, value)
+		placeholders = append(placeholders, placeholder)
+		args = append(args, valueArgs...)
+	}
+	if err := errs.merge(table.validateRecord(Context{StateInsert, c.DB, c.DataSet, table, rec, Field{}})); err != nil {
+		return nil, "", nil, err
+	}
+	if err := errs.orNil(); err != nil {
+		return nil, "", nil, err
 	}
 	query := "INSERT INTO %s(%s)VALUES(%s)"
 	query = fmt.Sprintf(query, table.Name, strings.Join(cols, sqlbuilder.ColSep), strings.Join(placeholders, sqlbuilder.ColSep))
-	rst, err := c.DB.Exec(query, args...)
-	return NewResult(Context{StateInsert, c.DB, c.DataSet, table, rec, Field{}}, rst), err
+	return rec, query, args, nil
 }
 
 // Update execute sql UPDATE.
@@ -146,55 +217,104 @@ func (table *Table) DefaultUpdate(c Context) (Result, error) {
 	for k, v := range c.Record {
 		rec[k] = v
 	}
-	var sets []string
-	var args []interface{}
+	values := make([]interface{}, len(table.Fields))
+	included := make([]bool, len(table.Fields))
+	errs := ValidationError{}
 	for i, field := range table.Fields {
 		if field.PrimaryKey || field.AutoInc {
 			continue
 		}
 		value, exist := c.Record[table.keys[i]]
+		if value, err = field.Coerce(value); err != nil {
+			if err = errs.Add(table.keys[i], field, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
 		if field.ReadOnly || !exist {
 			if field.OnUpdate == nil {
 				continue
 			}
 			value = nil
 		}
+		fc := Context{StateUpdate, c.DB, c.DataSet, table, rec, field}
 		if value == nil {
-			value = field.GetOnUpdate()
+			value = evalValue(field.OnUpdate, fc)
 		}
 		rec[table.keys[i]] = value
-		if err := validateField(Context{StateUpdate, c.DB, c.DataSet, table, rec, field}, value); err != nil {
-			return nil, err
+		values[i], included[i] = value, true
+	}
+	if err := table.validateValues(Context{StateUpdate, c.DB, c.DataSet, table, rec, Field{}}, values, included, errs); err != nil {
+		return nil, err
+	}
+	var sets []string
+	var args []interface{}
+	for i, field := range table.Fields {
+		if !included[i] {
+			continue
 		}
-		sets = append(sets, fmt.Sprintf("%s = %s", field.Name, Placeholder))
-		args = append(args, value)
+		placeholder, valueArgs := bindValue(values[i])
+		sets = append(sets, fmt.Sprintf("%s = %s", field.Name, placeholder))
+		args = append(args, valueArgs...)
+	}
+	if err := errs.merge(table.validateRecord(Context{StateUpdate, c.DB, c.DataSet, table, rec, Field{}})); err != nil {
+		return nil, err
+	}
+	if err := errs.orNil(); err != nil {
+		return nil, err
 	}
 	if len(sets) == 0 {
 		return nil, fmt.Errorf("Table %s: not enough columns to update", table.Name)
 	}
 	query := "UPDATE %s SET %s WHERE %s"
 	query = fmt.Sprintf(query, table.Name, strings.Join(sets, sqlbuilder.ColSep), whereQuery)
-	rst, err := c.DB.Exec(query, append(args, whereArgs...)...)
-	return NewResult(Context{StateUpdate, c.DB, c.DataSet, table, rec, Field{}}, rst), err
+	return table.exec(Context{StateUpdate, c.DB, c.DataSet, table, rec, Field{}}, query, append(args, whereArgs...)...)
 }
 
-// Validate field rules.
-func validateField(c Context, value interface{}) error {
-	field := c.Field
-	for _, v := range field.Validations {
-		if rule, ok := v.(ValidationRule); ok {
-			rule.SetContext(c)
+// Validate and encode the values of included fields. The values of all fields are resolved in c.Record first,
+// so the rules see the final values of the other fields, e.g. the Default of a field declared later.
+// The fields which fail validation are excluded.
+func (table *Table) validateValues(c Context, values []interface{}, included []bool, errs ValidationError) error {
+	for i, field := range table.Fields {
+		if !included[i] {
+			continue
 		}
-	}
-	if err := validation.Validate(value, field.Validations...); err != nil {
-		if e, ok := err.(validation.Error); ok {
-			return e.SetMessage(fmt.Sprintf(ErrorFormatOnValidation, field.GetTitle(), e))
+		fc := Context{c.State, c.DB, c.DataSet, table, c.Record, field}
+		if err := validateField(fc, values[i]); err != nil {
+			if err = errs.Add(table.keys[i], field, err); err != nil {
+				return err
+			}
+			included[i] = false
+			continue
+		}
+		value, err := field.encode(values[i])
+		if err != nil {
+			return err
 		}
-		return err
+		values[i] = value
 	}
 	return nil
 }
 
+// Validate field rules, Null is validated as nil, Expr is not validated.
+func validateField(c Context, value interface{}) error {
+	if _, ok := value.(Expr); ok {
+		return nil
+	}
+	if value == Null {
+		value = nil
+	}
+	return validation.Validate(value, bindRules(c, c.Field.Validations)...)
+}
+
+// Format validation error with field title.
+func fieldError(field Field, err error) error {
+	if e, ok := err.(validation.Error); ok {
+		return e.SetMessage(fmt.Sprintf(ErrorFormatOnValidation, field.GetTitle(), e))
+	}
+	return err
+}
+
 // Delete execute sql DELETE;
 func (table *Table) Delete(db DB, record Map) (Result, error) {
 	c := Context{StateDelete, db, table, table, record, Field{}}
@@ -209,11 +329,60 @@ func (table *Table) DefaultDelete(c Context) (Result, error) {
 	}
 
 	query = fmt.Sprintf("DELETE FROM %s WHERE %s", table.Name, query)
-	rst, err := c.DB.Exec(query, args...)
-	return NewResult(Context{StateDelete, c.DB, c.DataSet, table, c.Record, Field{}}, rst), err
+	return table.exec(Context{StateDelete, c.DB, c.DataSet, table, c.Record, Field{}}, query, args...)
+}
+
+// Execute INSERT/UPDATE/DELETE sql of c.State. If the dialect supports RETURNING clause,
+// the final record is fetched by the same statement, otherwise Result.Record(true) requery it.
+func (table *Table) exec(c Context, query string, args ...interface{}) (Result, error) {
+	if !DefaultDialect.returning(c.State) {
+		rst, err := dbExec(c.DB, query, args...)
+		return NewResult(c, rst), err
+	}
+	query += " RETURNING " + strings.Join(table.cols, sqlbuilder.ColSep)
+	maps, err := mapQuery(false, table.fieldsMap, c.DB, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var record = Map{}
+	for k, v := range c.Record {
+		record[k] = v
+	}
+	if len(maps) > 0 {
+		for k, v := range maps[0] {
+			record[k] = v
+		}
+	}
+	c.Record = record
+	return returnedResult{c, int64(len(maps))}, nil
 }
 
-// WherePrimaryKey get where sql by primary key in record.
+// Get query the record by primary key in key, return ErrNotFound if it does not exist.
+func (table *Table) Get(db DB, key Map) (Map, error) {
+	query, args, err := table.WherePrimaryKey(false, false, key)
+	if err != nil {
+		return nil, err
+	}
+	return getRecord(table, db, sqlbuilder.Select().Where(query), args...)
+}
+
+// ExistsKey check the record of primary key in key exists.
+func (table *Table) ExistsKey(db DB, key Map) (bool, error) {
+	query, args, err := table.WherePrimaryKey(false, false, key)
+	if err != nil {
+		return false, err
+	}
+	count, err := table.Count(db, sqlbuilder.Select().Where(query), args...)
+	return count > 0, err
+}
+
+// Reload requery the record by primary key, return a copy of record with the values in database.
+func (table *Table) Reload(db DB, record Map) (Map, error) {
+	return reloadRecord(table, db, record)
+}
+
+// WherePrimaryKey get where sql by primary key in record, set reversed is true to get the sql
+// that excludes the record, e.g. id <> ? or NOT (a = ? AND b = ?) of composite primary key.
 func (table *Table) WherePrimaryKey(hasTableName, reversed bool, record Map) (condition string, args []interface{}, err error) {
 	if err = table.Open(); err != nil {
 		return
@@ -223,7 +392,7 @@ func (table *Table) WherePrimaryKey(hasTableName, reversed bool, record Map) (co
 		return
 	}
 	operator := "="
-	if reversed {
+	if reversed && len(table.primaryKey) == 1 {
 		operator = "<>"
 	}
 	var conditions []string
@@ -242,28 +411,131 @@ func (table *Table) WherePrimaryKey(hasTableName, reversed bool, record Map) (co
 		args = append(args, value)
 	}
 	condition = strings.Join(conditions, " AND ")
+	if reversed && len(conditions) > 1 {
+		condition = "NOT (" + condition + ")"
+	}
+	return
+}
+
+// Find query records by example. The keys of example are Map keys of fields,
+// a nil or Null value, or a value coerced to Null like "" of Nullable field, matches NULL, a slice value matches IN, a string value with wildcard * or % matches LIKE,
+// and the other values match equality. The conditions are combined with the WHERE clause of selector by AND.
+func (table *Table) Find(db DB, example Map, selector sqlbuilder.Selector, args ...interface{}) ([]Map, error) {
+	query, exampleArgs, err := table.WhereExample(false, example)
+	if err != nil {
+		return nil, err
+	}
+	if query != "" {
+		selector = selector.WhereAndGroup(query)
+		args = append(args, exampleArgs...)
+	}
+	return table.Select(db, selector, args...)
+}
+
+// WhereExample get where sql by example record, see Find.
+func (table *Table) WhereExample(hasTableName bool, example Map) (condition string, args []interface{}, err error) {
+	if err = table.Open(); err != nil {
+		return
+	}
+	for key := range example {
+		if _, ok := table.fieldIndex(key); !ok {
+			err = fmt.Errorf("Unknown field %s in example", key)
+			return
+		}
+	}
+	var conditions []string
+	for i, field := range table.Fields {
+		value, exist := example[table.keys[i]]
+		if !exist {
+			continue
+		}
+		column := field.Name
+		if hasTableName {
+			column = table.Name + "." + column
+		}
+		if isNull(value) {
+			conditions = append(conditions, column+" IS NULL")
+			continue
+		}
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			if rv.Len() == 0 {
+				conditions = append(conditions, "1 = 0")
+				continue
+			}
+			var placeholders []string
+			hasNull := false
+			for j := 0; j < rv.Len(); j++ {
+				item, e := field.Coerce(rv.Index(j).Interface())
+				if e != nil {
+					err = fieldError(field, e)
+					return
+				}
+				if isNull(item) {
+					hasNull = true
+					continue
+				}
+				placeholders = append(placeholders, Placeholder)
+				args = append(args, item)
+			}
+			switch {
+			case len(placeholders) == 0:
+				conditions = append(conditions, column+" IS NULL")
+			case hasNull:
+				conditions = append(conditions, fmt.Sprintf("(%s IN (%s) OR %s IS NULL)", column, strings.Join(placeholders, sqlbuilder.ColSep), column))
+			default:
+				conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, sqlbuilder.ColSep)))
+			}
+			continue
+		}
+		if s, ok := value.(string); ok && strings.ContainsAny(s, "*%") {
+			conditions = append(conditions, column+" LIKE "+Placeholder)
+			args = append(args, strings.Replace(s, "*", "%", -1))
+			continue
+		}
+		if value, err = field.Coerce(value); err != nil {
+			err = fieldError(field, err)
+			return
+		}
+		if isNull(value) {
+			conditions = append(conditions, column+" IS NULL")
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = %s", column, Placeholder))
+		args = append(args, value)
+	}
+	condition = strings.Join(conditions, " AND ")
 	return
 }
 
-// Count query SELECT COUNT(*).
+// Get the index of field by Map key.
+func (table *Table) fieldIndex(key string) (int, bool) {
+	for i, k := range table.keys {
+		if k == key {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// Count query SELECT COUNT(*), ORDER BY, LIMIT and OFFSET of selector are ignored.
 func (table *Table) Count(db DB, selector sqlbuilder.Selector, args ...interface{}) (int64, error) {
 	if err := table.Open(); err != nil {
 		return 0, err
 	}
-	query := selector.Columns("COUNT(*)").From(table.Name).SQL()
-	row := db.QueryRow(query, args...)
-	var count int64
-	if err := row.Scan(&count); err != nil {
-		return 0, err
-	}
-	return count, nil
+	args, _ = splitIncludes(args)
+	return countQuery(db, selector.From(table.Name).CountSQL(), args...)
+}
+
+// Page query a page of records, page starts from 1.
+func (table *Table) Page(db DB, selector sqlbuilder.Selector, page, size int64, args ...interface{}) (*Paged, error) {
+	return selectPage(table, db, selector, page, size, args...)
 }
 
 // CountValue query SELECT COUNT(*) by column value. used for detect duplicate value.
 func (table *Table) CountValue(db DB, field Field, value interface{}, selector sqlbuilder.Selector, args ...interface{}) (int64, error) {
 	column := field.Name
 	condition := "= ?"
-	if value == nil {
+	if isNull(value) {
 		condition = "IS NULL"
 	} else {
 		switch v := value.(type) {
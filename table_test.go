@@ -0,0 +1,207 @@
+package godac
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"godac/sqlbuilder"
+	"reflect"
+	"strings"
+	"testing"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func TestWhereExample(t *testing.T) {
+	table := &Table{Name: "users", Fields: []Field{
+		{Name: "id", PrimaryKey: true, Type: TypeInt}, {Name: "user_name"}, {Name: "role"}, {Name: "deleted_at"},
+	}}
+	condition, args, err := table.WhereExample(true, Map{
+		"id": []interface{}{1.0, "2"}, "userName": "jo*", "role": "admin", "deletedAt": nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "users.id IN (?, ?) AND users.user_name LIKE ? AND users.role = ? AND users.deleted_at IS NULL"
+	if condition != want {
+		t.Errorf("condition = %s, want %s", condition, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(1), int64(2), "jo%", "admin"}) {
+		t.Errorf("args = %#v", args)
+	}
+	if _, _, err := table.WhereExample(false, Map{"password": "x"}); err == nil {
+		t.Error("unknown key should fail")
+	}
+
+	notes := &Table{Name: "notes", Fields: []Field{
+		{Name: "id", Type: TypeInt, Nullable: true}, {Name: "note", Type: TypeString, Nullable: true},
+	}}
+	condition, args, err = notes.WhereExample(false, Map{"id": []interface{}{"", 3.0}, "note": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "(id IN (?) OR id IS NULL) AND note IS NULL"; condition != want || !reflect.DeepEqual(args, []interface{}{int64(3)}) {
+		t.Errorf("condition = %s %v, want %s", condition, args, want)
+	}
+	if condition, _, _ = notes.WhereExample(false, Map{"id": []interface{}{""}}); condition != "id IS NULL" {
+		t.Errorf("condition = %s", condition)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	errs := ValidationError{}
+	if err := errs.Add("endDate", Field{Name: "end_date"}, validation.ErrRequired); err != nil {
+		t.Fatal(err)
+	}
+	errs.Add("code", Field{Name: "code", Title: "Code"}, validation.ErrLengthTooLong.SetParams(map[string]interface{}{"max": 3}))
+	if err := errs.Add("code", Field{Name: "code"}, errors.New("db error")); err == nil {
+		t.Error("Add should return non-validation error")
+	}
+	if got, want := errs.Error(), "Code: the length must be no more than 3; End Date: cannot be blank"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if errs["endDate"].Code != "validation_required" || errs.Code() != 422 {
+		t.Errorf("errs = %#v", errs)
+	}
+}
+
+func TestFindScope(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	table := &Table{Name: "users", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "role"}}}
+	if _, err := table.Find(db, Map{"role": "admin"}, sqlbuilder.Select().Where("id = ? OR id = ?"), 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SELECT id, role FROM users WHERE (id = ? OR id = ?) AND (role = ?) [1 2 admin]"}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestExplicitNull(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	notes := &Table{Name: "notes", Fields: []Field{
+		{Name: "id", PrimaryKey: true}, {Name: "note", Default: "none"}, {Name: "updated", OnUpdate: "now"},
+	}}
+	var record Map
+	if err := json.Unmarshal([]byte(`{"id":1,"note":null}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["note"] != Null {
+		t.Fatalf("note = %#v", record["note"])
+	}
+	if _, err := notes.Insert(db, record); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.Insert(db, Map{"id": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.Update(db, Map{"id": 1, "updated": Null}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.Update(db, Map{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"INSERT INTO notes(id, note, updated)VALUES(?, ?, ?) [1 <nil> <nil>]",
+		"INSERT INTO notes(id, note, updated)VALUES(?, ?, ?) [2 none <nil>]",
+		"UPDATE notes SET updated = ? WHERE id = ? [<nil> 1]",
+		"UPDATE notes SET updated = ? WHERE id = ? [now 1]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestExprValues(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	counters := &Table{Name: "counters", Fields: []Field{
+		{Name: "id", PrimaryKey: true}, {Name: "counter", Type: TypeInt, Validations: []validation.Rule{validation.Min(0)}},
+		{Name: "created", Default: Raw("CURRENT_TIMESTAMP")}, {Name: "updated", OnUpdate: Raw("NOW()")},
+	}}
+	if _, err := counters.Insert(db, Map{"id": 1, "counter": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := counters.Update(db, Map{"id": 1, "counter": Raw("counter + ?", 2)}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"INSERT INTO counters(id, counter, created, updated)VALUES(?, ?, CURRENT_TIMESTAMP, ?) [1 0 <nil>]",
+		"UPDATE counters SET counter = counter + ?, updated = NOW() WHERE id = ? [2 1]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+type testContextKey string
+
+func TestContextValueFunc(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	docs := &Table{Name: "docs", Fields: []Field{
+		{Name: "id", PrimaryKey: true},
+		{Name: "tenant_id", Default: ContextValueFunc(func(c Context) interface{} { return c.Value(testContextKey("tenant")) })},
+		{Name: "title"},
+		{Name: "slug", Default: func(c Context) interface{} {
+			return fmt.Sprintf("%v-%v-%s", c.Record["tenantID"], c.Record["title"], c.Field.Name)
+		}},
+	}}
+	ctx := context.WithValue(context.Background(), testContextKey("tenant"), int64(7))
+	if _, err := docs.Insert(WithContext(ctx, db), Map{"id": 1, "title": "go"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"INSERT INTO docs(id, tenant_id, title, slug)VALUES(?, ?, ?, ?) [1 7 go 7-go-slug]"}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestReturning(t *testing.T) {
+	defer func(dialect *Dialect) { DefaultDialect = dialect }(DefaultDialect)
+	DefaultDialect = PostgreSQL
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "INSERT") {
+			return []string{"id", "name", "created"}, [][]driver.Value{{int64(9), "jo", "today"}}
+		}
+		return nil, nil
+	})
+	users := &Table{Name: "users", Fields: []Field{
+		{Name: "id", PrimaryKey: true, AutoInc: true}, {Name: "name"}, {Name: "created", Default: Raw("now()")},
+	}}
+	result, err := users.Insert(db, Map{"name": "jo", "extra": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := result.Record(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Map{"id": int64(9), "name": "jo", "created": "today", "extra": true}); !reflect.DeepEqual(record, want) {
+		t.Errorf("record = %v", record)
+	}
+	if n, err := result.RowsAffected(); n != 1 || err != nil {
+		t.Errorf("RowsAffected = %d, %v", n, err)
+	}
+	if id, err := result.LastInsertId(); id != 9 || err != nil {
+		t.Errorf("LastInsertId = %d, %v", id, err)
+	}
+
+	result, err = users.Delete(db, Map{"id": int64(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 0 {
+		t.Errorf("RowsAffected of deleting missing record = %d", n)
+	}
+	if _, err := result.LastInsertId(); err == nil {
+		t.Error("LastInsertId of deleting missing record should fail")
+	}
+	want := []string{
+		"INSERT INTO users(name, created)VALUES($1, now()) RETURNING id, name, created [jo]",
+		"DELETE FROM users WHERE id = $1 RETURNING id, name, created [3]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
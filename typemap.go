@@ -0,0 +1,236 @@
+package godac
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeMapping converts a scanned column value to Go value.
+// src is not nil, it is one of int64, uint64, float64, bool, []byte, string and time.Time.
+type TypeMapping func(src interface{}, dialect *Dialect) (interface{}, error)
+
+// TypeMap is a registry of type mappings by database type name.
+type TypeMap map[string]TypeMapping
+
+// Clone return a copy of types, so the mappings registered on the copy do not change types.
+func (types TypeMap) Clone() TypeMap {
+	result := make(TypeMap, len(types))
+	for name, mapping := range types {
+		result[name] = mapping
+	}
+	return result
+}
+
+// TimeLayouts define the layouts of parsing DATETIME/TIMESTAMP text.
+var TimeLayouts = []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999Z07:00", "2006-01-02"}
+
+func mappingError(src interface{}, typeName string) error {
+	return fmt.Errorf("cannot convert %T value %v to %s", src, src, typeName)
+}
+
+// ToInt64 converts value to int64, or uint64 if it overflows int64.
+func ToInt64(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > 1<<63-1 {
+			return v, nil
+		}
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case []byte:
+		return ToInt64(string(v), dialect)
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return nil, mappingError(src, "int64")
+}
+
+// ToUint64 converts value to uint64.
+func ToUint64(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case uint64:
+		return v, nil
+	case int64:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case []byte:
+		return ToUint64(string(v), dialect)
+	case string:
+		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return nil, mappingError(src, "uint64")
+}
+
+// ToFloat64 converts value to float64.
+func ToFloat64(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case []byte:
+		return ToFloat64(string(v), dialect)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, mappingError(src, "float64")
+}
+
+// ToBool converts value to bool.
+func ToBool(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case []byte:
+		return ToBool(string(v), dialect)
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return nil, mappingError(src, "bool")
+}
+
+// ToString converts value to string.
+func ToString(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(TimeLayouts[0]), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// ToBytes converts value to []byte.
+func ToBytes(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, mappingError(src, "[]byte")
+}
+
+// ToTime converts value to time.Time in the location of dialect, the zero date is converted to nil.
+func ToTime(src interface{}, dialect *Dialect) (interface{}, error) {
+	loc := dialect.location()
+	switch v := src.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return nil, nil
+		}
+		return v.In(loc), nil
+	case []byte:
+		return ToTime(string(v), dialect)
+	case string:
+		if v == "" || strings.HasPrefix(v, "0000-00-00") {
+			return nil, nil
+		}
+		for _, layout := range TimeLayouts {
+			if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+				return t.In(loc), nil
+			}
+		}
+	}
+	return nil, mappingError(src, "time.Time")
+}
+
+// ToDecimal converts value to Decimal.
+func ToDecimal(src interface{}, dialect *Dialect) (interface{}, error) {
+	switch v := src.(type) {
+	case []byte:
+		return ParseDecimal(string(v))
+	case string:
+		return ParseDecimal(v)
+	case int64:
+		return Decimal(strconv.FormatInt(v, 10)), nil
+	case float64:
+		return Decimal(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	}
+	return nil, mappingError(src, "Decimal")
+}
+
+// ToJSON decodes JSON text.
+func ToJSON(src interface{}, dialect *Dialect) (interface{}, error) {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, mappingError(src, "JSON")
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Decimal is an exact decimal number in text.
+type Decimal string
+
+// ParseDecimal parse decimal number text.
+func ParseDecimal(s string) (Decimal, error) {
+	if _, ok := new(big.Rat).SetString(s); !ok || strings.Contains(s, "/") {
+		return "", fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal(s), nil
+}
+
+// Rat get the exact value of decimal.
+func (d Decimal) Rat() *big.Rat {
+	r, _ := new(big.Rat).SetString(string(d))
+	return r
+}
+
+// Float64 get the nearest float64 value of decimal.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(string(d), 64)
+	return f
+}
+
+// String implements fmt.Stringer.
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// MarshalJSON encode decimal as JSON number without loss of precision.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
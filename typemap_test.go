@@ -0,0 +1,80 @@
+package godac
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestTypeMappings(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	dialect := &Dialect{Name: "mysql", Types: MySQL.Types, Location: loc}
+
+	v, err := ToTime([]byte("2020-01-02 03:04:05"), dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2020, 1, 2, 3, 4, 5, 0, loc); !v.(time.Time).Equal(want) || v.(time.Time).Location() != loc {
+		t.Errorf("ToTime = %v, want %v", v, want)
+	}
+	if v, _ := ToTime("0000-00-00 00:00:00", dialect); v != nil {
+		t.Errorf("ToTime zero date = %v, want nil", v)
+	}
+
+	if v, _ := ToInt64([]byte("18446744073709551615"), dialect); v != uint64(18446744073709551615) {
+		t.Errorf("ToInt64 overflow = %#v", v)
+	}
+	if v, _ := ToUint64(int64(7), dialect); v != uint64(7) {
+		t.Errorf("ToUint64 = %#v", v)
+	}
+
+	d, err := ToDecimal([]byte("12345678901234567890.123456789"), dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if js, _ := d.(Decimal).MarshalJSON(); string(js) != "12345678901234567890.123456789" {
+		t.Errorf("Decimal JSON = %s", js)
+	}
+	if _, err := ParseDecimal("1/3"); err == nil {
+		t.Error("ParseDecimal(1/3) should fail")
+	}
+
+	j, err := ToJSON([]byte(`{"a":[1,"b"]}`), dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := j.(map[string]interface{}); !ok || len(m["a"].([]interface{})) != 2 {
+		t.Errorf("ToJSON = %#v", j)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b = '?' AND c IN (?, ?)"
+	if got := MySQL.Rebind(query); got != query {
+		t.Errorf("MySQL.Rebind = %s", got)
+	}
+	if got, want := PostgreSQL.Rebind(query), "SELECT * FROM t WHERE a = $1 AND b = '?' AND c IN ($2, $3)"; got != want {
+		t.Errorf("PostgreSQL.Rebind = %s, want %s", got, want)
+	}
+}
+
+func TestDialectTypes(t *testing.T) {
+	MariaDB.Types["TEST"] = ToString
+	defer delete(MariaDB.Types, "TEST")
+	if _, ok := MySQL.Types["TEST"]; ok {
+		t.Error("the mapping registered on MariaDB changes MySQL")
+	}
+
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"id", "n"}, [][]driver.Value{{int64(1), int64(5)}}
+	})
+	fake.types = map[string]fakeColumn{"id": {"BIGINT", false}, "n": {"BIGINT", true}}
+	rows, err := MapQuery(nil, db, "SELECT id, n FROM t")
+	if err != nil || rows[0]["n"] != int64(5) {
+		t.Fatal(rows, err)
+	}
+	rows, err = mapQuery(false, map[string]Field{"n": {Name: "n", Mapping: ToUint64}}, db, "SELECT id, n FROM t")
+	if err != nil || rows[0]["n"] != uint64(5) {
+		t.Errorf("rows = %v, err = %v", rows, err)
+	}
+}
@@ -1,7 +1,12 @@
 package godac
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"godac/sqlbuilder"
 )
 
@@ -12,9 +17,110 @@ type DB interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
 }
 
+type contextDB interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type ctxDB struct {
+	ctx context.Context
+	db  DB
+}
+
+// WithContext return a DB that carries ctx, queries are executed with ctx if db supports it,
+// e.g. *sql.DB and *sql.Tx. The values of ctx are available to Context.Value.
+func WithContext(ctx context.Context, db DB) DB {
+	if c, ok := db.(*ctxDB); ok {
+		db = c.db
+	}
+	return &ctxDB{ctx, db}
+}
+
+func (db *ctxDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if c, ok := db.db.(contextDB); ok {
+		return c.QueryContext(db.ctx, query, args...)
+	}
+	return db.db.Query(query, args...)
+}
+
+func (db *ctxDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	if c, ok := db.db.(contextDB); ok {
+		return c.QueryRowContext(db.ctx, query, args...)
+	}
+	return db.db.QueryRow(query, args...)
+}
+
+func (db *ctxDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if c, ok := db.db.(contextDB); ok {
+		return c.ExecContext(db.ctx, query, args...)
+	}
+	return db.db.Exec(query, args...)
+}
+
 // Map is a shortcut for map[string]interface{}, represents a database record.
 type Map map[string]interface{}
 
+// UnmarshalJSON decodes JSON object to Map, explicit null value is decoded to Null.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for k, v := range values {
+		if v == nil {
+			values[k] = Null
+		}
+	}
+	*m = values
+	return nil
+}
+
+// NullType is the type of Null.
+type NullType struct{}
+
+// Null is an explicit NULL record value, a nil value or absent key means to use the default value.
+var Null = NullType{}
+
+// Value implements driver.Valuer.
+func (NullType) Value() (driver.Value, error) {
+	return nil, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (NullType) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// Expr is a raw sql expression, it can be used as Default, OnUpdate or record value on INSERT and UPDATE,
+// e.g. Raw("CURRENT_TIMESTAMP"), Raw("counter + ?", 1). It is rendered inline with its own arguments,
+// and it is not coerced, validated or encoded. Use Result.Record(true) to get the final values.
+type Expr struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Raw create an Expr.
+func Raw(sql string, args ...interface{}) Expr {
+	return Expr{sql, args}
+}
+
+// Get the sql and arguments of value, it is Placeholder if value is not an Expr.
+func bindValue(value interface{}) (string, []interface{}) {
+	if expr, ok := value.(Expr); ok {
+		return expr.SQL, expr.Args
+	}
+	return Placeholder, []interface{}{value}
+}
+
+// Check the value is nil or Null.
+func isNull(value interface{}) bool {
+	return value == nil || value == Null
+}
+
 // State is dataset state.
 type State byte
 
@@ -36,9 +142,17 @@ type Context struct {
 	Field   Field
 }
 
+// Value get the value of context.Context carried by DB, see WithContext.
+func (c Context) Value(key interface{}) interface{} {
+	if db, ok := c.DB.(*ctxDB); ok {
+		return db.ctx.Value(key)
+	}
+	return nil
+}
+
 // Result is an extension of sql.Result.
 type Result interface {
-	Record(refresh bool) (Map, error) // Get last Insert/Update record, set refresh is true to requery from database.
+	Record(refresh bool) (Map, error) // Get last Insert/Update record, set refresh is true to requery from database, it resolves Expr values.
 	sql.Result
 }
 
@@ -68,18 +182,11 @@ func (r result) Record(refresh bool) (Map, error) {
 		}
 		record[table.keys[table.autoInc]] = id
 	}
-	query, args, err := table.WherePrimaryKey(false, false, record)
-	if err != nil {
-		return nil, err
-	}
-	maps, err := r.context.DataSet.Select(r.context.DB, sqlbuilder.Select().Where(query), args...)
-	if err != nil || len(maps) == 0 {
-		return nil, err
-	}
-	for k, v := range maps[0] {
-		record[k] = v
+	record, err := r.context.DataSet.Reload(r.context.DB, record)
+	if err == ErrNotFound {
+		return nil, nil
 	}
-	return record, nil
+	return record, err
 }
 
 func (r result) LastInsertId() (int64, error) {
@@ -89,13 +196,112 @@ func (r result) RowsAffected() (int64, error) {
 	return r.sqlResult.RowsAffected()
 }
 
+// The result of statement with RETURNING clause, the record is final.
+type returnedResult struct {
+	context Context
+	rows    int64
+}
+
+func (r returnedResult) Record(refresh bool) (Map, error) {
+	return r.context.Record, nil
+}
+
+func (r returnedResult) LastInsertId() (int64, error) {
+	table := r.context.Table
+	if r.context.State == StateInsert && table.autoInc >= 0 {
+		if id, ok := r.context.Record[table.keys[table.autoInc]].(int64); ok {
+			return id, nil
+		}
+	}
+	return 0, errors.New("LastInsertId is not available")
+}
+
+func (r returnedResult) RowsAffected() (int64, error) {
+	return r.rows, nil
+}
+
 // ActionFunc is customize func for Insert/Update/Delete
 type ActionFunc func(Context) (Result, error)
 
 // DataSet represents Table or Query.
 type DataSet interface {
 	Select(db DB, selector sqlbuilder.Selector, args ...interface{}) ([]Map, error)
+	Get(db DB, key Map) (Map, error)
+	ExistsKey(db DB, key Map) (bool, error)
+	Reload(db DB, record Map) (Map, error)
+	Count(db DB, selector sqlbuilder.Selector, args ...interface{}) (int64, error)
+	Page(db DB, selector sqlbuilder.Selector, page, size int64, args ...interface{}) (*Paged, error)
 	Insert(db DB, record Map) (Result, error)
 	Update(db DB, record Map) (Result, error)
 	Delete(db DB, record Map) (Result, error)
 }
+
+// Paged is a page of records.
+type Paged struct {
+	Rows    []Map `json:"rows"`
+	Total   int64 `json:"total"`   // Total number of records
+	Page    int64 `json:"page"`    // Page number, starts from 1
+	Size    int64 `json:"size"`    // Page size
+	Pages   int64 `json:"pages"`   // Page count
+	HasNext bool  `json:"hasNext"` // Has next page
+}
+
+// Query a page of dataset records.
+func selectPage(ds DataSet, db DB, selector sqlbuilder.Selector, page, size int64, args ...interface{}) (*Paged, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("Invalid page size %d", size)
+	}
+	if page < 1 {
+		page = 1
+	}
+	total, err := ds.Count(db, selector, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ds.Select(db, selector.Limit(size).Offset((page-1)*size), args...)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []Map{}
+	}
+	pages := (total + size - 1) / size
+	return &Paged{rows, total, page, size, pages, page < pages}, nil
+}
+
+// Query SELECT COUNT(*).
+func countQuery(db DB, query string, args ...interface{}) (int64, error) {
+	var count int64
+	if err := dbQueryRow(db, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Get the first record of dataset, return ErrNotFound if there is no record.
+func getRecord(ds DataSet, db DB, selector sqlbuilder.Selector, args ...interface{}) (Map, error) {
+	maps, err := ds.Select(db, selector, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(maps) == 0 {
+		return nil, ErrNotFound
+	}
+	return maps[0], nil
+}
+
+// Requery the record by Get, return a copy of record with the values in database.
+func reloadRecord(ds DataSet, db DB, record Map) (Map, error) {
+	values, err := ds.Get(db, record)
+	if err != nil {
+		return nil, err
+	}
+	var result = Map{}
+	for k, v := range record {
+		result[k] = v
+	}
+	for k, v := range values {
+		result[k] = v
+	}
+	return result, nil
+}
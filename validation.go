@@ -1,69 +1,373 @@
 package godac
 
 import (
+	"fmt"
 	"godac/sqlbuilder"
+	"strings"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
 // Validation errors.
 var (
-	ErrUnique = validation.NewError("unique", "already exists")
-	ErrIn     = validation.NewError("in", "not exists")
+	ErrUnique         = validation.NewError("unique", "already exists")
+	ErrUniqueTogether = validation.NewError("unique_together", "the combination of {{.titles}} already exists")
+	ErrIn             = validation.NewError("in", "not exists")
 )
 
-// ValidationRule is an extension of validation.Rule
+// RecordValidator validates the whole record in Context.Record, it is used for cross-field validation.
+// Use Context.Invalid to attach the error to fields.
+type RecordValidator func(Context) error
+
+// Run record validators of table, the record of update is merged into the record in database.
+func (table *Table) validateRecord(c Context) error {
+	if len(table.Validators) == 0 {
+		return nil
+	}
+	if c.State == StateUpdate {
+		record, err := table.Reload(c.DB, c.Record)
+		if err != nil {
+			return err
+		}
+		for k, v := range c.Record {
+			record[k] = v
+		}
+		c.Record = record
+	}
+	errs := ValidationError{}
+	for _, validator := range table.Validators {
+		if err := errs.merge(validator(c)); err != nil {
+			return err
+		}
+	}
+	return errs.orNil()
+}
+
+// Invalid return a ValidationError that attaches err to the fields of keys in c.Table.
+func (c Context) Invalid(err validation.Error, keys ...string) ValidationError {
+	errs := ValidationError{}
+	for _, key := range keys {
+		field := Field{Name: key, Key: key}
+		if c.Table != nil {
+			if i, ok := c.Table.fieldIndex(key); ok {
+				field = c.Table.Fields[i]
+			}
+		}
+		errs.Add(key, field, err)
+	}
+	return errs
+}
+
+// Record validation errors.
+var (
+	ErrRequireAny = validation.NewError("require_any", "one of them is required")
+	ErrNotBefore  = validation.NewError("not_before", "must not be less than {{.other}}")
+)
+
+// RequireAny return a record validator that checks one of the values of keys is not empty.
+func RequireAny(keys ...string) RecordValidator {
+	return func(c Context) error {
+		for _, key := range keys {
+			if value := c.Record[key]; !isNull(value) && !validation.IsEmpty(value) {
+				return nil
+			}
+		}
+		return c.Invalid(ErrRequireAny, keys...)
+	}
+}
+
+// NotBefore return a record validator that checks the value of key is not less than the value of otherKey,
+// e.g. NotBefore("endDate", "startDate"). The values can be time.Time, numbers, Decimal or strings,
+// it passes if one of them is empty.
+func NotBefore(key, otherKey string) RecordValidator {
+	return func(c Context) error {
+		value, other := c.Record[key], c.Record[otherKey]
+		if isNull(value) || isNull(other) {
+			return nil
+		}
+		less, ok := lessThan(value, other)
+		if !ok || !less {
+			return nil
+		}
+		title := otherKey
+		if c.Table != nil {
+			if i, ok := c.Table.fieldIndex(otherKey); ok {
+				title = c.Table.Fields[i].GetTitle()
+			}
+		}
+		return c.Invalid(ErrNotBefore.SetParams(map[string]interface{}{"other": title}), key)
+	}
+}
+
+// Compare a < b, ok is false if they are not comparable.
+func lessThan(a, b interface{}) (less, ok bool) {
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Before(y), true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x < y, true
+		}
+	case Decimal:
+		if y, ok := b.(Decimal); ok {
+			return x.Rat().Cmp(y.Rat()) < 0, true
+		}
+	}
+	x, errX := coerceFloat(a)
+	y, errY := coerceFloat(b)
+	if errX != nil || errY != nil {
+		return false, false
+	}
+	return x.(float64) < y.(float64), true
+}
+
+// ValidationRule is an extension of validation.Rule that validates value in Context.
+// The Context is passed to each ValidateContext call instead of being stored in the rule,
+// so a rule can be shared by fields, tables and goroutines.
+// The Context is bound to the rules of Field.Validations and WhenContext only, a ValidationRule
+// wrapped by the other rules, e.g. validation.When and validation.Each, fails with ErrContextRequired.
+// Use WhenContext, WhenInsert or WhenUpdate to apply it conditionally.
 type ValidationRule interface {
-	SetContext(Context)
+	ValidateContext(c Context, value interface{}) error
 	validation.Rule
 }
 
-// Unique is a validation rule that checks if a value is unique in table.
-var Unique = &uniqueRule{}
+// ErrContextRequired is returned when a ValidationRule is validated without Context.
+var ErrContextRequired = validation.NewError("context_required",
+	"the rule requires Context, wrap it by WhenContext instead of validation.When or Each")
+
+// Bind Context to the ValidationRule in rules, the others are returned as is.
+func bindRules(c Context, rules []validation.Rule) []validation.Rule {
+	bound := make([]validation.Rule, len(rules))
+	for i, v := range rules {
+		if rule, ok := v.(ValidationRule); ok {
+			bound[i] = boundRule{c, rule}
+		} else {
+			bound[i] = v
+		}
+	}
+	return bound
+}
 
-type uniqueRule struct {
+type boundRule struct {
 	context Context
+	rule    ValidationRule
 }
 
-func (rule *uniqueRule) SetContext(c Context) {
-	rule.context = c
+func (r boundRule) Validate(value interface{}) error {
+	return r.rule.ValidateContext(r.context, value)
 }
 
-func (rule *uniqueRule) Validate(value interface{}) (err error) {
-	var count int64
-	if rule.context.State == StateInsert {
-		count, err = rule.context.Table.CountValue(rule.context.DB, rule.context.Field, value, sqlbuilder.Select())
-	} else {
-		count, err = rule.context.Table.CountRecord(rule.context.DB, rule.context.Field, rule.context.Record, true, sqlbuilder.Select())
-	}
+// Unique is a validation rule that checks if a value is unique in table,
+// string values are compared without leading and trailing spaces, NULL values are not checked.
+var Unique = UniqueRule{}
+
+// UniqueRule is a unique validation rule, it can be combined with other fields and scoped by condition.
+type UniqueRule struct {
+	with       []string
+	where      string
+	whereArgs  []interface{}
+	ignoreCase bool
+	noTrim     bool
+}
+
+// With return a rule that checks the combination of field and the other fields of column names is unique,
+// e.g. Unique.With("company_id") on field code.
+func (rule UniqueRule) With(names ...string) UniqueRule {
+	rule.with = append(append([]string{}, rule.with...), names...)
+	return rule
+}
+
+// Where return a rule that checks uniqueness in the rows of condition only, e.g. Where("active = ?", true).
+func (rule UniqueRule) Where(condition string, args ...interface{}) UniqueRule {
+	rule.where = condition
+	rule.whereArgs = args
+	return rule
+}
+
+// IgnoreCase return a rule that compares string values case-insensitively.
+func (rule UniqueRule) IgnoreCase() UniqueRule {
+	rule.ignoreCase = true
+	return rule
+}
+
+// NoTrim return a rule that compares string values without TRIM.
+func (rule UniqueRule) NoTrim() UniqueRule {
+	rule.noTrim = true
+	return rule
+}
+
+// Validate implements validation.Rule.
+func (rule UniqueRule) Validate(value interface{}) error {
+	return ErrContextRequired
+}
+
+// ValidateContext implements ValidationRule.
+func (rule UniqueRule) ValidateContext(c Context, value interface{}) error {
+	table := c.Table
+	fields, values, err := recordValues(c, rule.with)
 	if err != nil {
-		return
+		return err
+	}
+	fields = append([]Field{c.Field}, fields...)
+	values = append([]interface{}{value}, values...)
+
+	selector := sqlbuilder.Select()
+	var args []interface{}
+	for i, field := range fields {
+		v := values[i]
+		if isNull(v) {
+			return nil
+		}
+		column := field.Name
+		if s, ok := v.(string); ok {
+			if !rule.noTrim {
+				column = fmt.Sprintf("TRIM(%s)", column)
+				s = strings.TrimSpace(s)
+			}
+			if rule.ignoreCase {
+				column = fmt.Sprintf("LOWER(%s)", column)
+				s = strings.ToLower(s)
+			}
+			v = s
+		}
+		selector = selector.WhereAnd(column + " = " + Placeholder)
+		args = append(args, v)
+	}
+	if rule.where != "" {
+		selector = selector.WhereAnd("(" + rule.where + ")")
+		args = append(args, rule.whereArgs...)
 	}
-	if count > 0 {
+	if c.State != StateInsert {
+		query, pkArgs, err := table.WherePrimaryKey(false, true, c.Record)
+		if err != nil {
+			return err
+		}
+		selector = selector.WhereAnd(query)
+		args = append(args, pkArgs...)
+	}
+	count, err := table.Count(c.DB, selector, args...)
+	if err != nil || count == 0 {
+		return err
+	}
+	if len(fields) == 1 {
 		return ErrUnique
 	}
-	return nil
+	titles := make([]string, len(fields))
+	for i, field := range fields {
+		titles[i] = field.GetTitle()
+	}
+	return ErrUniqueTogether.SetParams(map[string]interface{}{"titles": strings.Join(titles, ", ")})
 }
 
-// In return a validation rule that check field value is in table.
-func In(table *Table) *InRule {
-	return &InRule{table: table}
+// Get the fields of names in c.Table and their values in c.Record,
+// the absent values on UPDATE are got from the record in database.
+func recordValues(c Context, names []string) ([]Field, []interface{}, error) {
+	if err := c.Table.Open(); err != nil {
+		return nil, nil, err
+	}
+	var fields []Field
+	var values []interface{}
+	var current Map
+	for _, name := range names {
+		field, ok := c.Table.fieldsMap[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("Field %s is not in table %s", name, c.Table.Name)
+		}
+		value, exist := c.Record[field.GetKey()]
+		if !exist && c.State == StateUpdate {
+			if current == nil {
+				var err error
+				if current, err = c.Table.Get(c.DB, c.Record); err != nil {
+					return nil, nil, err
+				}
+			}
+			value = current[field.GetKey()]
+		}
+		fields = append(fields, field)
+		values = append(values, value)
+	}
+	return fields, values, nil
+}
+
+// In return a validation rule that checks the field value exists in table, like a foreign key.
+// The referenced column defaults to the field name, NULL values are not checked.
+func In(table *Table) InRule {
+	return InRule{table: table}
 }
 
 // InRule is in validation rule object.
 type InRule struct {
-	context Context
-	table   *Table
+	table     *Table
+	column    string
+	with      [][2]string
+	where     string
+	whereArgs []interface{}
 }
 
-// SetContext implements ValidationRule.
-func (rule *InRule) SetContext(c Context) {
-	rule.context = c
+// On return a rule that references column of the table instead of the field name.
+func (rule InRule) On(column string) InRule {
+	rule.column = column
+	return rule
+}
+
+// With return a rule of composite reference, the value of field name in the validated record
+// must match the column of the table too, e.g. In(products).On("id").With("company_id", "company_id").
+func (rule InRule) With(name, column string) InRule {
+	rule.with = append(append([][2]string{}, rule.with...), [2]string{name, column})
+	return rule
+}
+
+// Where return a rule that references the rows of condition only, e.g. Where("active = ?", true).
+func (rule InRule) Where(condition string, args ...interface{}) InRule {
+	rule.where = condition
+	rule.whereArgs = args
+	return rule
 }
 
 // Validate implements validation.Rule.
-func (rule *InRule) Validate(value interface{}) (err error) {
-	count, err := rule.table.CountRecord(rule.context.DB, rule.context.Field, rule.context.Record, false, sqlbuilder.Select())
+func (rule InRule) Validate(value interface{}) error {
+	return ErrContextRequired
+}
+
+// ValidateContext implements ValidationRule.
+func (rule InRule) ValidateContext(c Context, value interface{}) error {
+	column := rule.column
+	if column == "" {
+		column = c.Field.Name
+	}
+	columns := []string{column}
+	values := []interface{}{value}
+	if len(rule.with) > 0 {
+		names := make([]string, len(rule.with))
+		for i, pair := range rule.with {
+			names[i] = pair[0]
+			columns = append(columns, pair[1])
+		}
+		_, withValues, err := recordValues(c, names)
+		if err != nil {
+			return err
+		}
+		values = append(values, withValues...)
+	}
+
+	selector := sqlbuilder.Select()
+	var args []interface{}
+	for i, v := range values {
+		if isNull(v) {
+			return nil
+		}
+		selector = selector.WhereAnd(columns[i] + " = " + Placeholder)
+		args = append(args, v)
+	}
+	if rule.where != "" {
+		selector = selector.WhereAnd("(" + rule.where + ")")
+		args = append(args, rule.whereArgs...)
+	}
+	count, err := rule.table.Count(c.DB, selector, args...)
 	if err != nil {
 		return err
 	}
@@ -72,3 +376,35 @@ func (rule *InRule) Validate(value interface{}) (err error) {
 	}
 	return nil
 }
+
+// WhenInsert return a validation rule that applies rules on INSERT only.
+func WhenInsert(rules ...validation.Rule) *WhenRule {
+	return WhenContext(func(c Context) bool { return c.State == StateInsert }, rules...)
+}
+
+// WhenUpdate return a validation rule that applies rules on UPDATE only.
+func WhenUpdate(rules ...validation.Rule) *WhenRule {
+	return WhenContext(func(c Context) bool { return c.State == StateUpdate }, rules...)
+}
+
+// WhenContext return a validation rule that applies rules only if condition of Context is true,
+// it is the Context version of validation.When.
+func WhenContext(condition func(Context) bool, rules ...validation.Rule) *WhenRule {
+	return &WhenRule{condition: condition, rules: rules}
+}
+
+// WhenRule is a conditional validation rule in Context.
+type WhenRule struct {
+	condition func(Context) bool
+	rules     []validation.Rule
+}
+
+// Validate implements validation.Rule.
+func (rule *WhenRule) Validate(value interface{}) error {
+	return ErrContextRequired
+}
+
+// ValidateContext implements ValidationRule.
+func (rule *WhenRule) ValidateContext(c Context, value interface{}) error {
+	return validation.Validate(value, validation.When(rule.condition(c), bindRules(c, rule.rules)...))
+}
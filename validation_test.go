@@ -0,0 +1,276 @@
+package godac
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func TestRecordValidators(t *testing.T) {
+	table := &Table{Name: "events", Fields: []Field{
+		{Name: "id", PrimaryKey: true}, {Name: "start_date"}, {Name: "end_date"}, {Name: "email"}, {Name: "phone"},
+	}, Validators: []RecordValidator{NotBefore("endDate", "startDate"), RequireAny("email", "phone")}}
+	if err := table.Open(); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	c := Context{State: StateInsert, Table: table, Record: Map{"startDate": day, "endDate": day.AddDate(0, 0, -1), "phone": ""}}
+	err := table.validateRecord(c)
+	errs, ok := err.(ValidationError)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("err = %#v", err)
+	}
+	if got, want := errs.Error(), "Email: one of them is required; End Date: must not be less than Start Date; Phone: one of them is required"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	c.Record = Map{"startDate": day, "endDate": day, "email": "a@b.c"}
+	if err := table.validateRecord(c); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRecordValidatorsWithFieldErrors(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	table := &Table{Name: "events", Fields: []Field{
+		{Name: "id", PrimaryKey: true}, {Name: "start_date"}, {Name: "end_date", Validations: []validation.Rule{validation.Required}},
+		{Name: "email", Validations: []validation.Rule{validation.Length(0, 3)}}, {Name: "phone"},
+	}, Validators: []RecordValidator{NotBefore("startDate", "endDate"), RequireAny("email", "phone")}}
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	_, err := table.Insert(db, Map{"id": 1, "startDate": day, "endDate": day.AddDate(0, 0, 1), "email": "long"})
+	errs, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("err = %v", err)
+	}
+	if got, want := errs.Error(), "Email: the length must be no more than 3; Start Date: must not be less than End Date"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := fake.statements(); len(got) != 0 {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestWhenRules(t *testing.T) {
+	field := Field{Name: "password", Validations: []validation.Rule{WhenInsert(validation.Required)}}
+	if err := validateField(Context{State: StateInsert, Field: field}, ""); err == nil {
+		t.Error("password should be required on insert")
+	}
+	if err := validateField(Context{State: StateUpdate, Field: field}, ""); err != nil {
+		t.Errorf("password should not be required on update: %v", err)
+	}
+}
+
+func TestValidationRulesConcurrent(t *testing.T) {
+	companies := &Table{Name: "companies", Fields: []Field{{Name: "id", PrimaryKey: true}}}
+	items := &Table{Name: "items", Fields: []Field{
+		{Name: "id", PrimaryKey: true},
+		{Name: "code", Validations: []validation.Rule{WhenInsert(validation.Required), Unique.With("company_id")}},
+		{Name: "company_id", Validations: []validation.Rule{In(companies).On("id")}},
+	}}
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "FROM companies") {
+			return []string{"count"}, [][]driver.Value{{int64(1)}}
+		}
+		return nil, nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := fmt.Sprint("C", i)
+			if i%4 == 0 {
+				code = ""
+			}
+			_, err := items.Insert(db, Map{"id": i, "code": code, "companyID": 1})
+			if i%4 == 0 {
+				if errs, ok := err.(ValidationError); !ok || errs["code"].Code != "validation_required" {
+					t.Errorf("%d: err = %v", i, err)
+				}
+			} else if err != nil {
+				t.Errorf("%d: err = %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	inserts := 0
+	for _, statement := range fake.statements() {
+		if strings.HasPrefix(statement, "INSERT") {
+			inserts++
+		}
+	}
+	if inserts != 15 {
+		t.Errorf("inserts = %d, want 15", inserts)
+	}
+
+	if err := validation.Validate("x", Unique); errorCode(err) != errorCode(ErrContextRequired) {
+		t.Errorf("Unique without Context error = %v", err)
+	}
+}
+
+func TestContextRuleInOzzoWhen(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	table := &Table{Name: "items", Fields: []Field{
+		{Name: "id", PrimaryKey: true}, {Name: "code", Validations: []validation.Rule{validation.When(true, Unique)}},
+	}}
+	_, err := table.Insert(db, Map{"id": 1, "code": "A"})
+	errs, ok := err.(ValidationError)
+	if !ok || errs["code"].Code != "context_required" {
+		t.Fatalf("err = %#v", err)
+	}
+	if got := errs.Error(); got != "Code: the rule requires Context, wrap it by WhenContext instead of validation.When or Each" {
+		t.Errorf("Error() = %q", got)
+	}
+	table.Fields[1].Validations = []validation.Rule{WhenContext(func(Context) bool { return true }, Unique)}
+	if _, err := table.Insert(db, Map{"id": 1, "code": "A"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT COUNT(*) FROM items WHERE TRIM(code) = ? [A]",
+		"INSERT INTO items(id, code)VALUES(?, ?) [1 A]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+var testItems = &Table{Name: "items", Fields: []Field{
+	{Name: "id", PrimaryKey: true}, {Name: "code"}, {Name: "company_id", Title: "Company"},
+}}
+
+func TestUniqueRule(t *testing.T) {
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SELECT id,") {
+			return []string{"id", "code", "company_id"}, [][]driver.Value{{int64(3), "X", int64(5)}}
+		}
+		if strings.Contains(query, "<>") {
+			return []string{"count"}, [][]driver.Value{{int64(1)}}
+		}
+		return nil, nil
+	})
+	code := testItems.Fields[1]
+	insert := Context{StateInsert, db, testItems, testItems, Map{"code": " Ab ", "companyID": int64(1)}, code}
+	rule := Unique.With("company_id").Where("active = ? AND kind = ?", true, "k").IgnoreCase()
+	if err := rule.ValidateContext(insert, " Ab "); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unique.NoTrim().ValidateContext(insert, " Ab "); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unique.ValidateContext(insert, Null); err != nil {
+		t.Fatal(err)
+	}
+
+	update := Context{StateUpdate, db, testItems, testItems, Map{"id": int64(3), "code": "Ab"}, code}
+	err := Unique.With("company_id").ValidateContext(update, "Ab")
+	if err == nil || err.Error() != "the combination of Code, Company already exists" {
+		t.Errorf("update error = %v", err)
+	}
+
+	want := []string{
+		"SELECT COUNT(*) FROM items WHERE LOWER(TRIM(code)) = ? AND company_id = ? AND (active = ? AND kind = ?) [ab 1 true k]",
+		"SELECT COUNT(*) FROM items WHERE code = ? [ Ab ]",
+		"SELECT id, code, company_id FROM items WHERE id = ? [3]",
+		"SELECT COUNT(*) FROM items WHERE TRIM(code) = ? AND company_id = ? AND id <> ? [Ab 5 3]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestUniqueRuleOfDefaultAndCompositeKey(t *testing.T) {
+	db, fake := openFakeDB(t, nil)
+	tenant := ContextValueFunc(func(c Context) interface{} { return c.Value(testContextKey("tenant")) })
+	items := &Table{Name: "items", Fields: []Field{
+		{Name: "id", PrimaryKey: true},
+		{Name: "code", Validations: []validation.Rule{Unique.With("company_id")}},
+		{Name: "company_id", Default: tenant},
+	}}
+	ctx := context.WithValue(context.Background(), testContextKey("tenant"), int64(7))
+	if _, err := items.Insert(WithContext(ctx, db), Map{"id": 1, "code": "A"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := &Table{Name: "order_lines", Fields: []Field{
+		{Name: "order_id", PrimaryKey: true}, {Name: "line_no", PrimaryKey: true},
+		{Name: "sku", Validations: []validation.Rule{Unique.With("order_id")}},
+	}}
+	if _, err := lines.Update(db, Map{"orderID": 1, "lineNo": 2, "sku": "S"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT COUNT(*) FROM items WHERE TRIM(code) = ? AND company_id = ? [A 7]",
+		"INSERT INTO items(id, code, company_id)VALUES(?, ?, ?) [1 A 7]",
+		"SELECT COUNT(*) FROM order_lines WHERE TRIM(sku) = ? AND order_id = ? AND NOT (order_id = ? AND line_no = ?) [S 1 1 2]",
+		"UPDATE order_lines SET sku = ? WHERE order_id = ? AND line_no = ? [S 1 2]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestInRule(t *testing.T) {
+	products := &Table{Name: "products", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "company_id"}}}
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SELECT id,") {
+			return []string{"id", "code", "company_id"}, [][]driver.Value{{int64(3), "X", int64(5)}}
+		}
+		if strings.Contains(query, "FROM products") && args[0] == int64(8) {
+			return []string{"count"}, [][]driver.Value{{int64(1)}}
+		}
+		return nil, nil
+	})
+	field := Field{Name: "product_id"}
+	rule := In(products).On("id").With("company_id", "company_id").Where("active = ?", true)
+	insert := Context{StateInsert, db, testItems, testItems, Map{"companyID": int64(1)}, field}
+	if err := rule.ValidateContext(insert, int64(7)); errorCode(err) != errorCode(ErrIn) {
+		t.Errorf("insert error = %v", err)
+	}
+	update := Context{StateUpdate, db, testItems, testItems, Map{"id": int64(3)}, field}
+	if err := rule.ValidateContext(update, int64(8)); err != nil {
+		t.Errorf("update error = %v", err)
+	}
+	if err := In(products).ValidateContext(insert, Null); err != nil {
+		t.Errorf("null error = %v", err)
+	}
+
+	want := []string{
+		"SELECT COUNT(*) FROM products WHERE id = ? AND company_id = ? AND (active = ?) [7 1 true]",
+		"SELECT id, code, company_id FROM items WHERE id = ? [3]",
+		"SELECT COUNT(*) FROM products WHERE id = ? AND company_id = ? AND (active = ?) [8 5 true]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}
+
+func TestInRuleOfDefault(t *testing.T) {
+	db, fake := openFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"count"}, [][]driver.Value{{int64(1)}}
+	})
+	products := &Table{Name: "products", Fields: []Field{{Name: "id", PrimaryKey: true}, {Name: "company_id"}}}
+	tenant := ContextValueFunc(func(c Context) interface{} { return c.Value(testContextKey("tenant")) })
+	lines := &Table{Name: "lines", Fields: []Field{
+		{Name: "id", PrimaryKey: true},
+		{Name: "product_id", Validations: []validation.Rule{In(products).On("id").With("company_id", "company_id")}},
+		{Name: "company_id", Default: tenant},
+	}}
+	ctx := context.WithValue(context.Background(), testContextKey("tenant"), int64(7))
+	if _, err := lines.Insert(WithContext(ctx, db), Map{"id": 1, "productID": 3}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"SELECT COUNT(*) FROM products WHERE id = ? AND company_id = ? [3 7]",
+		"INSERT INTO lines(id, product_id, company_id)VALUES(?, ?, ?) [1 3 7]",
+	}
+	if got := fake.statements(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q", got)
+	}
+}